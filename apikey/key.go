@@ -0,0 +1,52 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// keys look like lk_<prefix>_<secret>, the prefix identifies the key and is safe to log
+	keyScheme    = "lk"
+	keySeparator = "_"
+	prefixBytes  = 6
+	secretBytes  = 32
+)
+
+func generateKey() (prefix string, secret string, err error) {
+	if prefix, err = randomHex(prefixBytes); err != nil {
+		return "", "", err
+	}
+	if secret, err = randomHex(secretBytes); err != nil {
+		return "", "", err
+	}
+	return prefix, secret, nil
+}
+
+func formatKey(prefix string, secret string) string {
+	return keyScheme + keySeparator + prefix + keySeparator + secret
+}
+
+func parseKey(raw string) (prefix string, secret string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(raw), keySeparator)
+	if len(parts) != 3 || parts[0] != keyScheme || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+// hashSecret the secret is 256 random bits, a plain sha256 is enough to make the stored value useless
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,177 @@
+package apikey
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"github.com/google/wire"
+	"github.com/lib/pq"
+	"lake-go/tenant"
+	"time"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvideStore,
+	)
+
+	ErrInvalidKey  = errors.New("invalid api key")
+	ErrKeyNotFound = errors.New("api key not found")
+)
+
+const (
+	columns = `id, prefix, name, owner_id, tenant, scopes, created_at, expires_at, last_used_at, revoked_at`
+
+	// lastUsedResolution avoids a write on every request of busy clients
+	lastUsedResolution = time.Minute
+)
+
+// APIKey is the stored metadata of a key, the secret itself is only known at creation
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Prefix     string     `json:"prefix"`
+	Name       string     `json:"name"`
+	OwnerID    string     `json:"ownerId"`
+	Tenant     string     `json:"tenant,omitempty"` // requests with the key are limited to this tenant
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+// ProvideStore the api_keys table is created by the migrations, see lake-go/migrate
+func ProvideStore(ctx context.Context, db *sql.DB) (*Store, error) {
+	return &Store{
+		db: db,
+	}, nil
+}
+
+// Create stores a new key of the tenant and returns it together with the plaintext key, which can't be recovered later
+func (s *Store) Create(ctx context.Context, ownerID string, tenantID string, name string, scopes []string, expiresAt *time.Time) (*APIKey, string, error) {
+	prefix, secret, err := generateKey()
+	if err != nil {
+		return nil, "", err
+	}
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	row := s.db.QueryRowContext(ctx,
+		`INSERT INTO api_keys (prefix, secret_hash, name, owner_id, tenant, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING `+columns,
+		prefix, hashSecret(secret), name, ownerID, tenantID, pq.Array(scopes), expiresAt)
+	key, err := scanKey(row)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, formatKey(prefix, secret), nil
+}
+
+// List returns the keys of the owner including revoked and expired ones, newest first
+func (s *Store) List(ctx context.Context, ownerID string) ([]*APIKey, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+columns+` FROM api_keys WHERE owner_id = $1 ORDER BY created_at DESC`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke revokes a key of the owner, ErrKeyNotFound is returned for unknown or already revoked keys
+func (s *Store) Revoke(ctx context.Context, ownerID string, id int64) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND owner_id = $2 AND revoked_at IS NULL`, id, ownerID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+// Verify checks a plaintext key, ErrInvalidKey is returned for unknown, revoked or expired keys
+func (s *Store) Verify(ctx context.Context, raw string) (*APIKey, error) {
+	prefix, secret, ok := parseKey(raw)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	var secretHash string
+	row := s.db.QueryRowContext(ctx, `SELECT `+columns+`, secret_hash FROM api_keys WHERE prefix = $1`, prefix)
+	key, err := scanKey(row, &secretHash)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(secretHash), []byte(hashSecret(secret))) != 1 {
+		return nil, ErrInvalidKey
+	}
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt)) {
+		return nil, ErrInvalidKey
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) > lastUsedResolution {
+		go s.touch(context.WithoutCancel(ctx), key.ID)
+	}
+	return key, nil
+}
+
+// touch runs after the request, ctx keeps its values like the tenant but not its cancellation
+func (s *Store) touch(ctx context.Context, id int64) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if _, err := s.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = now() WHERE id = $1`, id); err != nil {
+		tenant.GetLogger(ctx, "apikey.Store").Warne(ctx, "update api key last used found error", err, "id", id)
+	}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanKey(row scanner, extra ...interface{}) (*APIKey, error) {
+	key := &APIKey{}
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	dest := append([]interface{}{
+		&key.ID, &key.Prefix, &key.Name, &key.OwnerID, &key.Tenant, pq.Array(&key.Scopes),
+		&key.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	key.ExpiresAt = nullTime(expiresAt)
+	key.LastUsedAt = nullTime(lastUsedAt)
+	key.RevokedAt = nullTime(revokedAt)
+	return key, nil
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
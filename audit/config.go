@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"context"
+	"github.com/tyeryan/l-common-util/config"
+	"time"
+)
+
+type AuditConfig struct {
+	Enable bool `configdefault:"true" configstruct:"AUDIT_CONFIG_ENABLE"`
+	// BufferSize events waiting for the writer, Record blocks once it is full
+	BufferSize int32 `configdefault:"4096" configstruct:"AUDIT_CONFIG_BUFFER_SIZE" configvalidate:"min=1"`
+	BatchSize  int32 `configdefault:"100" configstruct:"AUDIT_CONFIG_BATCH_SIZE" configvalidate:"min=1"`
+	// FlushIntervalInMs flushes partial batches, it bounds how long an event stays in memory
+	FlushIntervalInMs int32 `configdefault:"1000" configstruct:"AUDIT_CONFIG_FLUSH_INTERVAL_IN_MS" configvalidate:"duration=10ms.."`
+	// EnqueueTimeoutInMs is how long Record waits on a full buffer before the event is dropped
+	EnqueueTimeoutInMs int32 `configdefault:"200" configstruct:"AUDIT_CONFIG_ENQUEUE_TIMEOUT_IN_MS" configvalidate:"duration=0s.."`
+	// MaxPageSize caps the limit of GET /v1/audit
+	MaxPageSize int32 `configdefault:"500" configstruct:"AUDIT_CONFIG_MAX_PAGE_SIZE" configvalidate:"min=1"`
+}
+
+func ProvideAuditConfig(ctx context.Context, configStore config.ConfigStore) (*AuditConfig, error) {
+	cnf := AuditConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
+}
+
+func (cnf *AuditConfig) flushInterval() time.Duration {
+	return time.Duration(cnf.FlushIntervalInMs) * time.Millisecond
+}
+
+func (cnf *AuditConfig) enqueueTimeout() time.Duration {
+	return time.Duration(cnf.EnqueueTimeoutInMs) * time.Millisecond
+}
@@ -0,0 +1,100 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeDenied  = "denied"
+	OutcomeFailure = "failure"
+
+	ActionLogin = "auth.login"
+
+	// genesisHash is the previous hash of the first event of the chain
+	genesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+)
+
+// Event is one entry of the audit trail. Hash covers all fields and the hash of the previous
+// event, so changing, removing or reordering stored events breaks the chain.
+type Event struct {
+	ID         int64             `json:"id"`
+	OccurredAt time.Time         `json:"occurredAt"`
+	Tenant     string            `json:"tenant,omitempty"`
+	Actor      string            `json:"actor"`
+	Stan       string            `json:"stan,omitempty"`
+	Action     string            `json:"action"`
+	Resource   string            `json:"resource"`
+	Outcome    string            `json:"outcome"`
+	Detail     map[string]string `json:"detail,omitempty"`
+	PrevHash   string            `json:"prevHash"`
+	Hash       string            `json:"hash"`
+}
+
+// OutcomeOf maps an http status to the outcome of an event
+func OutcomeOf(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return OutcomeDenied
+	case status >= http.StatusBadRequest:
+		return OutcomeFailure
+	default:
+		return OutcomeSuccess
+	}
+}
+
+// sanitize drops NUL bytes and invalid utf-8 of the client supplied strings, postgres rejects
+// both and would fail the whole batch. It runs before the hash, so the stored event verifies.
+func (e *Event) sanitize() {
+	for _, s := range []*string{&e.Tenant, &e.Actor, &e.Stan, &e.Action, &e.Resource, &e.Outcome} {
+		*s = sanitizeString(*s)
+	}
+	if len(e.Detail) == 0 {
+		return
+	}
+	detail := make(map[string]string, len(e.Detail))
+	for key, value := range e.Detail {
+		detail[sanitizeString(key)] = sanitizeString(value)
+	}
+	e.Detail = detail
+}
+
+func sanitizeString(s string) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(s, "\uFFFD"), "\x00", "")
+}
+
+// computeHash hashes the length prefixed fields, so no two different events share an encoding.
+// The time is hashed in microseconds as that is what postgres keeps.
+func (e *Event) computeHash() (string, error) {
+	detail := []byte{}
+	if len(e.Detail) > 0 {
+		var err error
+		// map keys are sorted by encoding/json
+		if detail, err = json.Marshal(e.Detail); err != nil {
+			return "", err
+		}
+	}
+
+	h := sha256.New()
+	for _, field := range [][]byte{
+		[]byte(e.PrevHash),
+		[]byte(e.OccurredAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)),
+		[]byte(e.Tenant),
+		[]byte(e.Actor),
+		[]byte(e.Stan),
+		[]byte(e.Action),
+		[]byte(e.Resource),
+		[]byte(e.Outcome),
+		detail,
+	} {
+		h.Write([]byte(strconv.Itoa(len(field)) + ":"))
+		h.Write(field)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+// Filter records every request as an access event, the action is the method and the route
+// pattern and the resource the path. It goes after the authentication so the actor is known.
+func (r *Recorder) Filter() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, req *http.Request) {
+			ww := middleware.NewWrapResponseWriter(w, req.ProtoMajor)
+			defer func() {
+				route := req.URL.Path
+				if rctx := chi.RouteContext(req.Context()); rctx != nil && rctx.RoutePattern() != "" {
+					route = rctx.RoutePattern()
+				}
+				status := ww.Status()
+				rvr := recover()
+				if rvr != nil {
+					status = http.StatusInternalServerError
+				} else if status == 0 {
+					status = http.StatusOK
+				}
+				r.Record(req.Context(), &Event{
+					Action:   req.Method + " " + route,
+					Resource: req.URL.Path,
+					Outcome:  OutcomeOf(status),
+					Detail:   map[string]string{"status": strconv.Itoa(status)},
+				})
+				if rvr != nil {
+					panic(rvr)
+				}
+			}()
+			next.ServeHTTP(ww, req)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
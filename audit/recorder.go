@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"context"
+	"github.com/google/wire"
+	ctxutil "github.com/tyeryan/l-protocol/context"
+	logutil "github.com/tyeryan/l-protocol/log"
+	"lake-go/tenant"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvideAuditConfig,
+		ProvideStore,
+		ProvideRecorder,
+	)
+)
+
+const (
+	appendTimeout = 10 * time.Second
+	appendRetries = 3
+)
+
+// Recorder writes audit events asynchronously in batches. When the writer falls behind the
+// buffer fills up and Record blocks for up to the enqueue timeout before it drops the event.
+type Recorder struct {
+	cnf    *AuditConfig
+	store  *Store
+	events chan *Event
+	done   chan struct{}
+
+	// mu guards events against Record sending on it while Close closes it
+	mu      sync.RWMutex
+	closed  bool
+	dropped int64
+}
+
+func ProvideRecorder(ctx context.Context, cnf *AuditConfig, store *Store) (*Recorder, error) {
+	r := &Recorder{
+		cnf:    cnf,
+		store:  store,
+		events: make(chan *Event, cnf.BufferSize),
+		done:   make(chan struct{}),
+	}
+	if !cnf.Enable {
+		r.closed = true
+		close(r.done)
+		return r, nil
+	}
+	go r.run()
+	return r, nil
+}
+
+// Record queues the event. Actor, stan and tenant are taken from the context unless set,
+// OccurredAt defaults to now.
+func (r *Recorder) Record(ctx context.Context, e *Event) {
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now()
+	}
+	if e.Actor == "" {
+		e.Actor, _ = ctxutil.Read(ctx, ctxutil.UserID)
+	}
+	if e.Stan == "" {
+		e.Stan, _ = ctxutil.Read(ctx, ctxutil.Stan)
+	}
+	if e.Tenant == "" {
+		e.Tenant, _ = tenant.FromContext(ctx)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return
+	}
+
+	select {
+	case r.events <- e:
+		return
+	default:
+	}
+
+	timer := time.NewTimer(r.cnf.enqueueTimeout())
+	defer timer.Stop()
+	select {
+	case r.events <- e:
+	case <-timer.C:
+		dropped := atomic.AddInt64(&r.dropped, 1)
+		tenant.GetLogger(ctx, "audit.Recorder").Errorw(ctx, "audit buffer full, event dropped",
+			"action", e.Action, "actor", e.Actor, "resource", e.Resource, "outcome", e.Outcome, "dropped", dropped)
+	}
+}
+
+// Close stops accepting events and waits until the queued ones are written
+func (r *Recorder) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.events)
+	}
+	r.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	batch := make([]*Event, 0, r.cnf.BatchSize)
+	ticker := time.NewTicker(r.cnf.flushInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-r.events:
+			if !ok {
+				r.flush(batch)
+				return
+			}
+			batch = append(batch, e)
+			if len(batch) >= int(r.cnf.BatchSize) {
+				r.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			r.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// flush writes the batch, retrying with backoff. Events of a batch which can't be written
+// are logged in full so they can still be recovered from the logs.
+func (r *Recorder) flush(batch []*Event) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx := ctxutil.NewContext(ctxutil.WithStan("audit-writer"))
+	log := logutil.GetLogger("audit.Recorder")
+
+	var err error
+	backoff := time.Second
+	for attempt := 1; attempt <= appendRetries; attempt++ {
+		appendCtx, cancel := context.WithTimeout(ctx, appendTimeout)
+		err = r.store.Append(appendCtx, batch)
+		cancel()
+		if err == nil {
+			return
+		}
+		log.Warne(ctx, "append audit events found error", err, "events", len(batch), "attempt", attempt)
+		if attempt < appendRetries {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	for _, e := range batch {
+		log.Errorw(ctx, "audit event not persisted", "occurredAt", e.OccurredAt, "tenant", e.Tenant, "actor", e.Actor,
+			"stan", e.Stan, "action", e.Action, "resource", e.Resource, "outcome", e.Outcome, "detail", e.Detail)
+	}
+	log.Alerte(ctx, "audit events lost", err, "events", len(batch))
+}
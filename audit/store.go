@@ -0,0 +1,276 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lake-go/db"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	columns = `id, occurred_at, tenant, actor, stan, action, resource, outcome, detail, prev_hash, hash`
+
+	// chainLockID serializes appends of all replicas, the chain would fork otherwise
+	chainLockID = 0x6c616b6561756474
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+)
+
+// Store keeps the hash chained audit trail in postgres, rows are only ever inserted
+type Store struct {
+	db *sql.DB
+}
+
+// ProvideStore the audit_events table is created by the migrations, see lake-go/migrate
+func ProvideStore(ctx context.Context, db *sql.DB) (*Store, error) {
+	return &Store{
+		db: db,
+	}, nil
+}
+
+// Append chains the events to the last stored one and inserts them in one transaction,
+// ID, PrevHash and Hash of the events are set on success
+func (s *Store) Append(ctx context.Context, events []*Event) error {
+	return db.InTx(ctx, s.db, nil, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, chainLockID); err != nil {
+			return err
+		}
+		prevHash := genesisHash
+		err := tx.QueryRowContext(ctx, `SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1`).Scan(&prevHash)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		stmt, err := tx.PrepareContext(ctx,
+			`INSERT INTO audit_events (occurred_at, tenant, actor, stan, action, resource, outcome, detail, prev_hash, hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, e := range events {
+			e.sanitize()
+			e.OccurredAt = e.OccurredAt.UTC().Truncate(time.Microsecond)
+			e.PrevHash = prevHash
+			if e.Hash, err = e.computeHash(); err != nil {
+				return err
+			}
+			detail, err := marshalDetail(e.Detail)
+			if err != nil {
+				return err
+			}
+			if err := stmt.QueryRowContext(ctx, e.OccurredAt, e.Tenant, e.Actor, e.Stan, e.Action, e.Resource,
+				e.Outcome, detail, e.PrevHash, e.Hash).Scan(&e.ID); err != nil {
+				return err
+			}
+			prevHash = e.Hash
+		}
+		return nil
+	})
+}
+
+// Query filters the trail, empty fields match everything but Tenant, which is always matched.
+// Resource matches a prefix when it ends with *.
+type Query struct {
+	Tenant   string
+	Actor    string
+	Action   string
+	Resource string
+	Outcome  string
+	From     *time.Time
+	To       *time.Time
+	// Before is the cursor, only events with a smaller id are returned
+	Before int64
+	Limit  int
+}
+
+// List returns the matching events newest first, next is the cursor of the following page
+// and 0 on the last one
+func (s *Store) List(ctx context.Context, q *Query) ([]*Event, int64, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	// an empty tenant only matches events recorded outside of any tenant
+	where("tenant = $%d", q.Tenant)
+	if q.Actor != "" {
+		where("actor = $%d", q.Actor)
+	}
+	if q.Action != "" {
+		where("action = $%d", q.Action)
+	}
+	if strings.HasSuffix(q.Resource, "*") {
+		where(`resource LIKE $%d ESCAPE '\'`, likeEscaper.Replace(strings.TrimSuffix(q.Resource, "*"))+"%")
+	} else if q.Resource != "" {
+		where("resource = $%d", q.Resource)
+	}
+	if q.Outcome != "" {
+		where("outcome = $%d", q.Outcome)
+	}
+	if q.From != nil {
+		where("occurred_at >= $%d", *q.From)
+	}
+	if q.To != nil {
+		where("occurred_at < $%d", *q.To)
+	}
+	if q.Before > 0 {
+		where("id < $%d", q.Before)
+	}
+
+	query := `SELECT ` + columns + ` FROM audit_events`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	// one more row than asked tells whether there is a next page
+	args = append(args, q.Limit+1)
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	events := []*Event{}
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	var next int64
+	if len(events) > q.Limit {
+		events = events[:q.Limit]
+		next = events[len(events)-1].ID
+	}
+	return events, next, nil
+}
+
+// Verification is the outcome of Verify. LastHash should be kept by the caller, comparing
+// it with a later run detects events removed from the end of the trail.
+type Verification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`
+	LastID   int64  `json:"lastId,omitempty"`
+	LastHash string `json:"lastHash,omitempty"`
+	// BrokenID is the first event which doesn't match the chain
+	BrokenID int64  `json:"brokenId,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Anchor is the LastID and LastHash of an earlier verification
+type Anchor struct {
+	ID   int64
+	Hash string
+}
+
+// Verify walks the trail from its start and recomputes every hash. The chain alone can't tell
+// when events were cut off its end, the optional anchor of an earlier run must still be in it.
+func (s *Store) Verify(ctx context.Context, anchor *Anchor) (*Verification, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+columns+` FROM audit_events ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	v := &Verification{Valid: true}
+	prevHash := genesisHash
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		v.Checked++
+
+		hash, err := e.computeHash()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case e.PrevHash != prevHash:
+			v.Valid, v.BrokenID, v.Reason = false, e.ID, "previous hash doesn't match the preceding event, events were removed or reordered"
+		case hash != e.Hash:
+			v.Valid, v.BrokenID, v.Reason = false, e.ID, "hash doesn't match the event, it was modified"
+		case anchor != nil && e.ID == anchor.ID && e.Hash != anchor.Hash:
+			v.Valid, v.BrokenID, v.Reason = false, e.ID, "hash doesn't match the anchor, the trail was rewritten"
+		}
+		if !v.Valid {
+			return v, nil
+		}
+		prevHash = e.Hash
+		v.LastID, v.LastHash = e.ID, e.Hash
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if anchor != nil && v.LastID < anchor.ID {
+		v.Valid, v.Reason = false, "anchor event is missing, events were removed from the end"
+	}
+	return v, nil
+}
+
+// EncodeCursor makes the opaque cursor of GET /v1/audit from the id of the last event of a page
+func EncodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func DecodeCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEvent(row scanner) (*Event, error) {
+	e := &Event{}
+	var detail []byte
+	if err := row.Scan(&e.ID, &e.OccurredAt, &e.Tenant, &e.Actor, &e.Stan, &e.Action, &e.Resource,
+		&e.Outcome, &detail, &e.PrevHash, &e.Hash); err != nil {
+		return nil, err
+	}
+	e.OccurredAt = e.OccurredAt.UTC()
+	if len(detail) > 0 {
+		if err := json.Unmarshal(detail, &e.Detail); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+func marshalDetail(detail map[string]string) (interface{}, error) {
+	if len(detail) == 0 {
+		return nil, nil
+	}
+	raw, err := json.Marshal(detail)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
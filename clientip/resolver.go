@@ -0,0 +1,80 @@
+package clientip
+
+import (
+	"context"
+	"github.com/google/wire"
+	"github.com/tyeryan/l-common-util/config"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"net/http"
+	"strings"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvideClientIPConfig,
+		ProvideResolver,
+	)
+)
+
+type ClientIPConfig struct {
+	// TrustedProxyHops number of proxies in front of us which append to X-Forwarded-For, 0 uses the remote address.
+	// Behind a proxy it must be set: with 0 every client shares the address of the proxy, so the login
+	// guard locks out everyone after a few failures and all clients share one public rate limit.
+	// The chart sets 1 for the istio ingress gateway, see .kubernetes/lake-api/values.yaml.
+	TrustedProxyHops int32 `configdefault:"0" configstruct:"CLIENT_IP_CONFIG_TRUSTED_PROXY_HOPS" configvalidate:"min=0"`
+}
+
+// Resolver finds the address of the client for the login guard, the rate limits and the audit trail
+type Resolver struct {
+	cnf *ClientIPConfig
+}
+
+func ProvideClientIPConfig(ctx context.Context, configStore config.ConfigStore) (*ClientIPConfig, error) {
+	cnf := ClientIPConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
+}
+
+func ProvideResolver(ctx context.Context, cnf *ClientIPConfig) (*Resolver, error) {
+	return &Resolver{
+		cnf: cnf,
+	}, nil
+}
+
+// FromRequest the address of the client, X-Forwarded-For is only trusted for the configured proxy hops
+func (res *Resolver) FromRequest(r *http.Request) string {
+	return res.From(r.Header.Get("X-Forwarded-For"), r.RemoteAddr)
+}
+
+// FromContext is FromRequest for grpc calls, it returns the client ip and the address of the peer
+func (res *Resolver) FromContext(ctx context.Context) (ip string, remoteAddr string) {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remoteAddr = p.Addr.String()
+	}
+	forwardedFor := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("x-forwarded-for"); len(values) > 0 {
+			forwardedFor = values[0]
+		}
+	}
+	return res.From(forwardedFor, remoteAddr), remoteAddr
+}
+
+// From is FromRequest for callers which have neither a request nor a grpc context
+func (res *Resolver) From(forwardedFor string, remoteAddr string) string {
+	if hops := int(res.cnf.TrustedProxyHops); hops > 0 && forwardedFor != "" {
+		addrs := strings.Split(forwardedFor, ",")
+		if len(addrs) >= hops {
+			return strings.TrimSpace(addrs[len(addrs)-hops])
+		}
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
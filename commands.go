@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"lake-go/audit"
+	"lake-go/migrate"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `usage: lake-go [command]
+
+without a command the service is started
+
+commands:
+  audit verify [-anchor id:hash]   check the hash chain of the audit trail
+  config print                     list every config key, its source and its effective value
+  migrate up [-steps n]            apply pending migrations, all of them by default
+  migrate down [-steps n]          revert applied migrations, the last one by default
+  migrate status                   list migrations and whether they are applied
+  migrate create [-dir dir] name   add empty up and down scripts of the next version
+`
+
+// runCommand runs a maintenance command instead of the service and returns the exit code
+func runCommand(ctx context.Context, args []string) int {
+	switch {
+	case len(args) >= 2 && args[0] == "audit" && args[1] == "verify":
+		return auditVerify(ctx, args[2:])
+	case len(args) >= 2 && args[0] == "config" && args[1] == "print":
+		return configPrint(ctx)
+	case len(args) >= 2 && args[0] == "migrate" && (args[1] == "up" || args[1] == "down"):
+		return migrateSteps(ctx, args[1], args[2:])
+	case len(args) >= 2 && args[0] == "migrate" && args[1] == "status":
+		return migrateStatus(ctx)
+	case len(args) >= 2 && args[0] == "migrate" && args[1] == "create":
+		return migrateCreate(args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+}
+
+// auditVerify prints the verification as json and exits with 1 when the trail was tampered with.
+// Keep lastId and lastHash of a run and pass them as -anchor to the next to detect truncation.
+func auditVerify(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("audit verify", flag.ContinueOnError)
+	rawAnchor := flags.String("anchor", "", "lastId:lastHash of an earlier verification")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	var anchor *audit.Anchor
+	if *rawAnchor != "" {
+		id, hash, _ := strings.Cut(*rawAnchor, ":")
+		parsedID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil || hash == "" {
+			fmt.Fprintln(os.Stderr, "anchor must be id:hash")
+			return 2
+		}
+		anchor = &audit.Anchor{ID: parsedID, Hash: hash}
+	}
+
+	store, err := injectAuditStore(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "connect to the audit trail failed:", err)
+		return 2
+	}
+	verification, err := store.Verify(ctx, anchor)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "verify audit trail failed:", err)
+		return 2
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	out.SetIndent("", "  ")
+	out.Encode(verification)
+	if !verification.Valid {
+		return 1
+	}
+	return 0
+}
+
+// configPrint lists the keys in the order of their configs with secrets masked, the failed
+// checks follow on stderr with exit code 1
+func configPrint(ctx context.Context) int {
+	store, err := injectConfigStore(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "load config failed:", err)
+		return 2
+	}
+	settings, err := store.Settings(knownConfigs()...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "decode config failed:", err)
+		return 2
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(out, "KEY\tSOURCE\tVALUE")
+	for _, s := range settings {
+		fmt.Fprintf(out, "%s\t%s\t%s\n", s.Key, s.Source, s.Value)
+	}
+	out.Flush()
+
+	if err := store.Check(knownConfigs()...); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid config:")
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func migrateSteps(ctx context.Context, direction string, args []string) int {
+	flags := flag.NewFlagSet("migrate "+direction, flag.ContinueOnError)
+	defaultSteps := 0
+	if direction == "down" {
+		defaultSteps = 1
+	}
+	steps := flags.Int("steps", defaultSteps, "number of migrations, 0 for all pending ones on up")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *steps < 0 || (direction == "down" && *steps == 0) {
+		fmt.Fprintln(os.Stderr, "steps must be positive")
+		return 2
+	}
+
+	migrator, err := injectMigrator(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "connect to the database failed:", err)
+		return 2
+	}
+	run := migrator.Up
+	if direction == "down" {
+		run = migrator.Down
+	}
+	migrations, err := run(ctx, *steps)
+	for _, m := range migrations {
+		fmt.Printf("%s %04d_%s\n", direction, m.Version, m.Name)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "migrate failed:", err)
+		return 1
+	}
+	if len(migrations) == 0 {
+		fmt.Println("nothing to migrate")
+	}
+	return 0
+}
+
+func migrateStatus(ctx context.Context) int {
+	migrator, err := injectMigrator(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "connect to the database failed:", err)
+		return 2
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "read migration status failed:", err)
+		return 1
+	}
+
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(out, "VERSION\tNAME\tSTATUS")
+	for _, s := range statuses {
+		state := "pending"
+		switch {
+		case s.Missing:
+			state = "applied " + s.AppliedAt.Format(time.RFC3339) + ", missing in this build"
+		case s.Modified:
+			state = "applied " + s.AppliedAt.Format(time.RFC3339) + ", modified since"
+		case s.AppliedAt != nil:
+			state = "applied " + s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(out, "%04d\t%s\t%s\n", s.Version, s.Name, state)
+	}
+	out.Flush()
+	return 0
+}
+
+func migrateCreate(args []string) int {
+	flags := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := flags.String("dir", migrate.SourceDir, "directory of the migrations")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	paths, err := migrate.Create(*dir, flags.Arg(0))
+	for _, path := range paths {
+		fmt.Println("created", path)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "create migration failed:", err)
+		return 1
+	}
+	return 0
+}
@@ -1,22 +1,26 @@
 package config
 
 import (
-	"github.com/spf13/viper"
+	"context"
+	commonconfig "github.com/tyeryan/l-common-util/config"
 	"lake-go/db"
+	"os"
+	"path/filepath"
 )
 
+// LoadConfig reads app.env of the directory and returns the database config,
+// variables of the environment take precedence over the file
 func LoadConfig(path string) (config db.DatabaseConfig, err error) {
-	viper.AddConfigPath(path)
-	viper.SetConfigType("env")
-	viper.SetConfigName("app")
-
-	viper.AutomaticEnv()
-
-	err = viper.ReadInConfig()
-	if err != nil {
+	file := filepath.Join(path, "app.env")
+	if _, err = os.Stat(file); err != nil {
 		return
 	}
 
-	err = viper.Unmarshal(&config)
+	ctx := context.Background()
+	store := &Store{decodeOption: commonconfig.ProvideDecodeOption(ctx)}
+	if err = store.Load(file, ""); err != nil {
+		return
+	}
+	err = store.GetConfig(&config)
 	return
 }
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+
+	masked = "********"
+)
+
+// secretWords mask the value of keys whose last word is one of them, like PASSWORD,
+// RedisConfig_Password or SERVER_CONFIG_ADMIN_TOKEN
+var secretWords = map[string]bool{
+	"PASSWORD": true,
+	"SECRET":   true,
+	"TOKEN":    true,
+}
+
+// Setting is the effective value of one key and where it came from
+type Setting struct {
+	Key    string `json:"key"`
+	Source string `json:"source"`
+	Value  string `json:"value"`
+}
+
+// Settings lists the keys of the configs in field order with secrets masked. The configs are
+// decoded but not validated, so a broken config can still be inspected.
+func (s *Store) Settings(vals ...interface{}) ([]*Setting, error) {
+	fileKeys := map[string]bool{}
+	s.mutex.Lock()
+	for key := range s.values {
+		fileKeys[strings.ToUpper(key)] = true
+	}
+	s.mutex.Unlock()
+
+	var settings []*Setting
+	for _, val := range vals {
+		if err := s.decode(val); err != nil {
+			return nil, fmt.Errorf("%s: %w", reflect.TypeOf(val).Elem().Name(), err)
+		}
+		v := reflect.ValueOf(val).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			key := configKey(v.Type().Field(i))
+			if key == "" {
+				continue
+			}
+			setting := &Setting{Key: key, Source: SourceDefault, Value: format(v.Field(i))}
+			// viper skips empty variables as well
+			if os.Getenv(strings.ToUpper(key)) != "" {
+				setting.Source = SourceEnv
+			} else if fileKeys[strings.ToUpper(key)] {
+				setting.Source = SourceFile
+			}
+			if secret(key) && setting.Value != "" {
+				setting.Value = masked
+			}
+			settings = append(settings, setting)
+		}
+	}
+	return settings, nil
+}
+
+func secret(key string) bool {
+	words := strings.FieldsFunc(strings.ToUpper(key), func(r rune) bool { return r == '_' })
+	return len(words) > 0 && secretWords[words[len(words)-1]]
+}
+
+// format writes slices the way they are configured, separated by commas
+func format(value reflect.Value) string {
+	if value.Kind() != reflect.Slice {
+		return fmt.Sprint(value.Interface())
+	}
+	items := make([]string, value.Len())
+	for i := range items {
+		items[i] = fmt.Sprint(value.Index(i).Interface())
+	}
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/wire"
+	"github.com/spf13/viper"
+	"github.com/subosito/gotenv"
+	commonconfig "github.com/tyeryan/l-common-util/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	// WireSet replaces config.WireSet of l-common-util
+	WireSet = wire.NewSet(
+		commonconfig.ProvideDecodeOption,
+		ProvideConfigStore,
+		wire.Bind(new(commonconfig.ConfigStore), new(*Store)),
+		ProvideWatcher,
+	)
+)
+
+const defaultTagName = "configdefault"
+
+// Store is the config.ConfigStore of lake-go. The store of l-common-util registers the whole
+// configstruct tag as key, so fields tagged "KEY,omitempty" got neither their default nor their
+// environment variable. Store registers the key in front of the options.
+//
+// Values come from the environment, then the config file and dir of WatchConfig, then the
+// configdefault of the field.
+type Store struct {
+	decodeOption viper.DecoderConfigOption
+	watch        *WatchConfig
+
+	mutex sync.Mutex
+	// values of the file and dir, kept to roll a rejected reload back
+	values map[string]string
+}
+
+// ProvideConfigStore loads the config file and dir named by the environment, see WatchConfig
+func ProvideConfigStore(ctx context.Context, decodeOption viper.DecoderConfigOption) (*Store, error) {
+	s := &Store{
+		decodeOption: decodeOption,
+		watch:        &WatchConfig{},
+	}
+	if err := s.GetConfig(s.watch); err != nil {
+		return nil, err
+	}
+	if err := s.Load(s.watch.File, s.watch.Dir); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetConfig decodes the environment and the loaded config file into the struct val points to,
+// fields missing in both get their configdefault. The failed configvalidate rules and the
+// Validate method of the config are returned as one error.
+func (s *Store) GetConfig(val interface{}) error {
+	if err := s.decode(val); err != nil {
+		return err
+	}
+	return validate(val)
+}
+
+func (s *Store) decode(val interface{}) error {
+	t := reflect.TypeOf(val)
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return errors.New("only accept struct pointer")
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := 0; i < t.Elem().NumField(); i++ {
+		field := t.Elem().Field(i)
+		if key := configKey(field); key != "" {
+			s.setDefault(key, field.Tag.Get(defaultTagName))
+		}
+	}
+	return viper.Unmarshal(val, s.decodeOption)
+}
+
+// Check decodes every config and joins the failures, so a deployment learns all of them at once
+func (s *Store) Check(vals ...interface{}) error {
+	var errs []error
+	for _, val := range vals {
+		if err := s.GetConfig(val); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", reflect.TypeOf(val).Elem().Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Store) SetDefault(key string, val interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.setDefault(key, val)
+}
+
+func (s *Store) setDefault(key string, val interface{}) {
+	viper.SetDefault(key, val)
+	viper.BindEnv(key)
+}
+
+// Load replaces the values of the previous file and dir. A missing file is skipped, a dir holds
+// one file per key like a mounted config map, its values win over the file.
+func (s *Store) Load(file string, dir string) error {
+	values := map[string]string{}
+	if file != "" {
+		env, err := readEnvFile(file)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		for key, value := range env {
+			values[key] = value
+		}
+	}
+	if dir != "" {
+		keys, err := readDir(dir)
+		if err != nil {
+			return err
+		}
+		for key, value := range keys {
+			values[key] = value
+		}
+	}
+	return s.set(values)
+}
+
+// set hands the values to viper below the environment, unlike viper.Set which would override it
+func (s *Store) set(values map[string]string) error {
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	viper.SetConfigType("json")
+	if err := viper.ReadConfig(bytes.NewReader(raw)); err != nil {
+		return err
+	}
+	s.values = values
+	return nil
+}
+
+func readEnvFile(file string) (gotenv.Env, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	env, err := gotenv.StrictParse(f)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	return env, nil
+}
+
+// readDir skips hidden entries, kubernetes keeps the versions of a config map in ..data dirs
+func readDir(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		name := filepath.Join(dir, entry.Name())
+		// config map keys are symlinks into the current ..data dir
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		value, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		values[entry.Name()] = strings.TrimRight(string(value), "\r\n")
+	}
+	return values, nil
+}
+
+// configKey is the key of a struct field, empty for fields without a configstruct tag
+func configKey(field reflect.StructField) string {
+	key, _, _ := strings.Cut(field.Tag.Get(commonconfig.DefStructTagName), ",")
+	return key
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validateTagName lists the rules of a field, separated by commas:
+//
+//	required       the value is not empty or zero
+//	min=n, max=n   bounds of numbers, the length of strings and slices
+//	url            an absolute url with scheme and host
+//	hostport       host:port like l-auth:8888, the host may be empty like :8080
+//	duration=a..b  bounds like 1s..5m of a time.ParseDuration string or of an int field named
+//	               ...InSec or ...InMs, either bound may be left out
+//
+// Rules other than required skip empty strings, optional fields stay optional.
+const validateTagName = "configvalidate"
+
+// validate checks the fields of the decoded struct val points to, then its Validate method
+func validate(val interface{}) error {
+	v := reflect.ValueOf(val).Elem()
+	var errs []error
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		rules := field.Tag.Get(validateTagName)
+		if rules == "" {
+			continue
+		}
+		key := configKey(field)
+		for _, rule := range strings.Split(rules, ",") {
+			if err := validateRule(field, v.Field(i), strings.TrimSpace(rule)); err != nil {
+				errs = append(errs, fmt.Errorf("%s %w", key, err))
+			}
+		}
+	}
+	if v, ok := val.(validator); ok {
+		if err := v.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateRule(field reflect.StructField, value reflect.Value, rule string) error {
+	name, arg, _ := strings.Cut(rule, "=")
+	if name == "required" {
+		if value.IsZero() || (value.Kind() == reflect.Slice && value.Len() == 0) {
+			return errors.New("is required")
+		}
+		return nil
+	}
+	if value.Kind() == reflect.String && value.String() == "" {
+		return nil
+	}
+
+	switch name {
+	case "min", "max":
+		bound, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Errorf("has an invalid rule %q", rule)
+		}
+		n, what := size(value)
+		if name == "min" && n < bound {
+			return fmt.Errorf("%s must be at least %s, got %v", what, arg, n)
+		}
+		if name == "max" && n > bound {
+			return fmt.Errorf("%s must be at most %s, got %v", what, arg, n)
+		}
+	case "url":
+		u, err := url.Parse(value.String())
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("must be an absolute url, got %q", value.String())
+		}
+	case "hostport":
+		_, port, err := net.SplitHostPort(value.String())
+		if n, portErr := strconv.ParseUint(port, 10, 16); err != nil || portErr != nil || n == 0 {
+			return fmt.Errorf("must be host:port, got %q", value.String())
+		}
+	case "duration":
+		return validateDuration(field, value, rule, arg)
+	default:
+		return fmt.Errorf("has an unknown rule %q", rule)
+	}
+	return nil
+}
+
+func validateDuration(field reflect.StructField, value reflect.Value, rule string, arg string) error {
+	var d time.Duration
+	switch {
+	case value.Kind() == reflect.String:
+		var err error
+		if d, err = time.ParseDuration(value.String()); err != nil {
+			return fmt.Errorf("must be a duration like 30s, got %q", value.String())
+		}
+	case value.CanInt() && strings.HasSuffix(field.Name, "InSec"):
+		d = time.Duration(value.Int()) * time.Second
+	case value.CanInt() && strings.HasSuffix(field.Name, "InMs"):
+		d = time.Duration(value.Int()) * time.Millisecond
+	default:
+		return fmt.Errorf("has rule %q on a field without a unit", rule)
+	}
+
+	lower, upper, ok := strings.Cut(arg, "..")
+	if !ok {
+		return fmt.Errorf("has an invalid rule %q", rule)
+	}
+	if lower != "" {
+		bound, err := time.ParseDuration(lower)
+		if err != nil {
+			return fmt.Errorf("has an invalid rule %q", rule)
+		}
+		if d < bound {
+			return fmt.Errorf("must be at least %s, got %s", bound, d)
+		}
+	}
+	if upper != "" {
+		bound, err := time.ParseDuration(upper)
+		if err != nil {
+			return fmt.Errorf("has an invalid rule %q", rule)
+		}
+		if d > bound {
+			return fmt.Errorf("must be at most %s, got %s", bound, d)
+		}
+	}
+	return nil
+}
+
+// size is the number min and max compare, with what it measures for the message
+func size(value reflect.Value) (float64, string) {
+	switch {
+	case value.CanInt():
+		return float64(value.Int()), "value"
+	case value.CanUint():
+		return float64(value.Uint()), "value"
+	case value.CanFloat():
+		return value.Float(), "value"
+	case value.Kind() == reflect.String || value.Kind() == reflect.Slice:
+		return float64(value.Len()), "length"
+	}
+	return 0, "value"
+}
+
+// validator is implemented by configs which check more than their fields one by one
+type validator interface {
+	Validate() error
+}
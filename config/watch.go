@@ -0,0 +1,300 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	logutil "github.com/tyeryan/l-protocol/log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// WatchConfig is read from the environment only, it names the file and dir the rest is loaded from
+type WatchConfig struct {
+	// File is an env file like app.env, skipped when it doesn't exist
+	File string `configdefault:"app.env" configstruct:"CONFIG_WATCH_FILE"`
+	// Dir is optional, one file per key named like the key
+	Dir string `configstruct:"CONFIG_WATCH_DIR"`
+	// Enable reloads on changes of File and Dir, SIGHUP reloads either way
+	Enable       bool  `configdefault:"true" configstruct:"CONFIG_WATCH_ENABLE"`
+	DebounceInMs int32 `configdefault:"500" configstruct:"CONFIG_WATCH_DEBOUNCE_IN_MS" configvalidate:"duration=0s.."`
+}
+
+// Watcher reloads the file and dir of the Store and hands the changed configs to their
+// subscribers. A reload is applied only when every changed config decodes and validates,
+// otherwise the previous values are restored and no subscriber sees it.
+//
+// Variables of the environment still win over the file, keys set there can't be reloaded.
+type Watcher struct {
+	store *Store
+	cnf   *WatchConfig
+
+	mutex         sync.Mutex
+	subscriptions []*subscription
+	fsWatcher     *fsnotify.Watcher
+
+	// files named by configs, see SubscribeFile. They have their own mutex as they change
+	// while a reload holds mutex.
+	filesMutex sync.Mutex
+	files      map[string]bool
+}
+
+type subscription struct {
+	name string
+	// prepare decodes the config, GetConfig validates it. It returns nil when the config didn't change.
+	prepare func() (apply func(), err error)
+}
+
+func ProvideWatcher(ctx context.Context, store *Store) *Watcher {
+	return &Watcher{
+		store: store,
+		cnf:   store.watch,
+	}
+}
+
+// Subscribe decodes a T and calls apply with a new T whenever a reload changes it. The current T
+// is returned, the subscriber built from the same values doesn't need apply at start.
+func Subscribe[T any](w *Watcher, name string, apply func(cnf *T)) (*T, error) {
+	current := new(T)
+	if err := w.store.GetConfig(current); err != nil {
+		return nil, err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.subscriptions = append(w.subscriptions, &subscription{
+		name: name,
+		prepare: func() (func(), error) {
+			next := new(T)
+			if err := w.store.GetConfig(next); err != nil {
+				return nil, err
+			}
+			if reflect.DeepEqual(current, next) {
+				return nil, nil
+			}
+			return func() {
+				current = next
+				apply(next)
+			}, nil
+		},
+	})
+	return current, nil
+}
+
+// SubscribeFile is Subscribe for a config which names a file, like a policy. load parses the
+// content of the file named by path, nil when path returns "", and apply gets the result whenever
+// the config or the content of the file changes. The file is watched like the env file, a load
+// error rejects the reload. The current result is returned.
+func SubscribeFile[T any, V any](w *Watcher, name string, path func(cnf *T) string, load func(raw []byte) (V, error), apply func(value V)) (V, error) {
+	var zero V
+	read := func() (string, []byte, V, error) {
+		cnf := new(T)
+		if err := w.store.GetConfig(cnf); err != nil {
+			return "", nil, zero, err
+		}
+		file := path(cnf)
+		var raw []byte
+		if file != "" {
+			var err error
+			if raw, err = os.ReadFile(file); err != nil {
+				return "", nil, zero, err
+			}
+		}
+		value, err := load(raw)
+		if err != nil {
+			return "", nil, zero, fmt.Errorf("load %s: %w", file, err)
+		}
+		return file, raw, value, nil
+	}
+
+	currentFile, currentRaw, current, err := read()
+	if err != nil {
+		return zero, err
+	}
+	if err := w.watchFile(currentFile); err != nil {
+		return zero, err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.subscriptions = append(w.subscriptions, &subscription{
+		name: name,
+		prepare: func() (func(), error) {
+			file, raw, value, err := read()
+			if err != nil {
+				return nil, err
+			}
+			if file == currentFile && bytes.Equal(raw, currentRaw) {
+				return nil, nil
+			}
+			return func() {
+				currentFile, currentRaw = file, raw
+				if err := w.watchFile(file); err != nil {
+					logutil.GetLogger("config.Watcher").Errore(context.Background(), "watch "+file+" found error", err)
+				}
+				apply(value)
+			}, nil
+		},
+	})
+	return current, nil
+}
+
+// watchFile adds the file to the watched ones, its parent dir is watched once Start ran
+func (w *Watcher) watchFile(file string) error {
+	if file == "" {
+		return nil
+	}
+	file = filepath.Clean(file)
+
+	w.filesMutex.Lock()
+	defer w.filesMutex.Unlock()
+	if w.files[file] {
+		return nil
+	}
+	if w.files == nil {
+		w.files = map[string]bool{}
+	}
+	if w.fsWatcher != nil {
+		if err := w.fsWatcher.Add(filepath.Dir(file)); err != nil {
+			return err
+		}
+	}
+	w.files[file] = true
+	return nil
+}
+
+func (w *Watcher) watched(name string) bool {
+	w.filesMutex.Lock()
+	defer w.filesMutex.Unlock()
+	return w.files[name]
+}
+
+// Reload reads the file and dir again, a rejected reload is logged and returned
+func (w *Watcher) Reload(ctx context.Context) error {
+	log := logutil.GetLogger("config.Watcher")
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	previous := w.store.values
+	if err := w.store.Load(w.cnf.File, w.cnf.Dir); err != nil {
+		log.Errore(ctx, "reload config found error, keeping the current config", err)
+		return err
+	}
+
+	var applies []func()
+	var changed []string
+	var errs []error
+	for _, s := range w.subscriptions {
+		apply, err := s.prepare()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+			continue
+		}
+		if apply != nil {
+			applies = append(applies, apply)
+			changed = append(changed, s.name)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		if rollbackErr := w.store.set(previous); rollbackErr != nil {
+			log.Errore(ctx, "restore config found error", rollbackErr)
+		}
+		log.Errore(ctx, "invalid config rejected, keeping the current config", err)
+		return err
+	}
+
+	for _, apply := range applies {
+		apply()
+	}
+	log.Infow(ctx, "config reloaded", "changed", changed)
+	return nil
+}
+
+// Start watches the file and dir when enabled, and the files of SubscribeFile. Changes within the
+// debounce interval cause one reload.
+// Without debounce every change reloads right away.
+func (w *Watcher) Start(ctx context.Context) error {
+	w.filesMutex.Lock()
+	files := len(w.files)
+	w.filesMutex.Unlock()
+	if !w.cnf.Enable || (w.cnf.File == "" && w.cnf.Dir == "" && files == 0) {
+		return nil
+	}
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// the parent dir is watched as editors and kubernetes replace files instead of writing them
+	file, dir := "", ""
+	if w.cnf.File != "" {
+		file = filepath.Clean(w.cnf.File)
+		if err := fsWatcher.Add(filepath.Dir(file)); err != nil {
+			fsWatcher.Close()
+			return err
+		}
+	}
+	if w.cnf.Dir != "" {
+		dir = filepath.Clean(w.cnf.Dir)
+		if err := fsWatcher.Add(dir); err != nil {
+			fsWatcher.Close()
+			return err
+		}
+	}
+	w.filesMutex.Lock()
+	for watched := range w.files {
+		if err := fsWatcher.Add(filepath.Dir(watched)); err != nil {
+			w.filesMutex.Unlock()
+			fsWatcher.Close()
+			return err
+		}
+	}
+	w.fsWatcher = fsWatcher
+	w.filesMutex.Unlock()
+
+	go func() {
+		log := logutil.GetLogger("config.Watcher")
+		debounce := time.Duration(w.cnf.DebounceInMs) * time.Millisecond
+		// created stopped, a timer of 0 could fire before Stop and cause a reload without change
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		for {
+			select {
+			case event, ok := <-fsWatcher.Events:
+				if !ok {
+					timer.Stop()
+					return
+				}
+				name := filepath.Clean(event.Name)
+				if name != file && (dir == "" || filepath.Dir(name) != dir) && !w.watched(name) {
+					continue
+				}
+				if debounce == 0 {
+					_ = w.Reload(ctx)
+					continue
+				}
+				timer.Reset(debounce)
+			case err, ok := <-fsWatcher.Errors:
+				if !ok {
+					return
+				}
+				log.Errore(ctx, "watch config found error", err)
+			case <-timer.C:
+				_ = w.Reload(ctx)
+			}
+		}
+	}()
+	return nil
+}
+
+// Close stops watching, reloads on SIGHUP keep working
+func (w *Watcher) Close() error {
+	if w.fsWatcher == nil {
+		return nil
+	}
+	return w.fsWatcher.Close()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/tyeryan/l-common-util/apm"
+	"github.com/tyeryan/l-common-util/cache"
+	"lake-go/audit"
+	"lake-go/clientip"
+	"lake-go/config"
+	"lake-go/db"
+	"lake-go/filter"
+	"lake-go/grpcclient"
+	"lake-go/grpcserver"
+	"lake-go/health"
+	"lake-go/logging"
+	"lake-go/loginguard"
+	"lake-go/migrate"
+	"lake-go/policy"
+	"lake-go/ratelimit"
+	"lake-go/server"
+	"lake-go/session"
+	"lake-go/tenant"
+	"lake-go/tracing"
+)
+
+// knownConfigs returns a new instance of every config the service decodes, they are checked at
+// start and listed by config print. Add new configs here as well as to their provider.
+func knownConfigs() []interface{} {
+	return []interface{}{
+		&config.WatchConfig{},
+		&logging.LogConfig{},
+		&server.ServerConfig{},
+		&grpcserver.GRPCServerConfig{},
+		&health.HealthConfig{},
+		&db.DatabaseConfig{},
+		&migrate.MigrateConfig{},
+		&cache.RedisConfig{},
+		&apm.ApmConfig{},
+		&tracing.TracingConfig{},
+		&grpcclient.LAuthConfig{},
+		&filter.AuthFilterConfig{},
+		&filter.RequestLoggerConfig{},
+		&filter.CORSConfig{},
+		&tenant.TenantConfig{},
+		&session.SessionConfig{},
+		&policy.PolicyConfig{},
+		&loginguard.LoginGuardConfig{},
+		&clientip.ClientIPConfig{},
+		&ratelimit.RateLimitConfig{},
+		&audit.AuditConfig{},
+	}
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"github.com/tyeryan/l-common-util/config"
+	"time"
+)
+
+// DatabaseConfig lake postgres database, see app.env
+type DatabaseConfig struct {
+	Type     string `configdefault:"postgres" configstruct:"TYPE" configvalidate:"required"`
+	Host     string `configdefault:"localhost" configstruct:"HOST" configvalidate:"required"`
+	Port     int    `configdefault:"5432" configstruct:"PORT" configvalidate:"min=1,max=65535"`
+	User     string `configstruct:"DB_USER"`
+	Password string `configstruct:"PASSWORD"`
+	Name     string `configdefault:"lake" configstruct:"DB_NAME" configvalidate:"required"`
+	SSLMode  string `configdefault:"disable" configstruct:"SSL_MODE"`
+	// MaxOpenConns caps the pool, 0 is unlimited. MaxIdleConns above it is lowered to it by database/sql.
+	MaxIdleConns int32 `configdefault:"3" configstruct:"MAX_IDLE_CONNS" configvalidate:"min=0"`
+	MaxOpenConns int32 `configdefault:"10" configstruct:"MAX_OPEN_CONNS" configvalidate:"min=1"`
+	// ConnMaxLifetimeInSec recycles connections, so fail overs and dns changes are picked up
+	ConnMaxLifetimeInSec int32 `configdefault:"1800" configstruct:"DB_CONN_MAX_LIFETIME_IN_SEC" configvalidate:"duration=0s.."`
+	ConnMaxIdleTimeInSec int32 `configdefault:"300" configstruct:"DB_CONN_MAX_IDLE_TIME_IN_SEC" configvalidate:"duration=0s.."`
+	// SlowQueryThresholdInMs queries taking longer are logged, 0 disables the log
+	SlowQueryThresholdInMs int32 `configdefault:"200" configstruct:"DB_SLOW_QUERY_THRESHOLD_IN_MS" configvalidate:"duration=0s.."`
+}
+
+func ProvideDatabaseConfig(ctx context.Context, configStore config.ConfigStore) (*DatabaseConfig, error) {
+	cnf := DatabaseConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
+}
+
+func (c *DatabaseConfig) slowQueryThreshold() time.Duration {
+	return time.Duration(c.SlowQueryThresholdInMs) * time.Millisecond
+}
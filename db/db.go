@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"github.com/google/wire"
+	"github.com/lib/pq"
+	logutil "github.com/tyeryan/l-protocol/log"
+	"net/url"
+	"time"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvideDatabaseConfig,
+		ProvideDB,
+	)
+)
+
+const pingTimeout = 5 * time.Second
+
+// ProvideDB opens the connection pool with the limits of the config, queries slower than
+// the threshold are logged
+func ProvideDB(ctx context.Context, cnf *DatabaseConfig) (*sql.DB, error) {
+	log := logutil.GetLogger("ProvideDB")
+
+	connector, err := newConnector(cnf)
+	if err != nil {
+		log.Errore(ctx, "open database found error", err)
+		return nil, err
+	}
+	conn := sql.OpenDB(connector)
+	conn.SetMaxOpenConns(int(cnf.MaxOpenConns))
+	conn.SetMaxIdleConns(int(cnf.MaxIdleConns))
+	conn.SetConnMaxLifetime(time.Duration(cnf.ConnMaxLifetimeInSec) * time.Second)
+	conn.SetConnMaxIdleTime(time.Duration(cnf.ConnMaxIdleTimeInSec) * time.Second)
+
+	pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	if err := conn.PingContext(pingCtx); err != nil {
+		log.Errore(ctx, "connect to database found error", err, "host", cnf.Host, "port", cnf.Port, "name", cnf.Name)
+		conn.Close()
+		return nil, err
+	}
+	log.Infow(ctx, "database connected", "host", cnf.Host, "port", cnf.Port, "name", cnf.Name,
+		"maxOpenConns", cnf.MaxOpenConns, "maxIdleConns", cnf.MaxIdleConns)
+	return conn, nil
+}
+
+func newConnector(cnf *DatabaseConfig) (driver.Connector, error) {
+	if cnf.Type != "postgres" {
+		return nil, fmt.Errorf("unsupported database type %q, only postgres is supported", cnf.Type)
+	}
+	connector, err := pq.NewConnector(cnf.DSN())
+	if err != nil {
+		return nil, err
+	}
+	if cnf.SlowQueryThresholdInMs <= 0 {
+		return connector, nil
+	}
+	return &slowQueryConnector{Connector: connector, threshold: cnf.slowQueryThreshold()}, nil
+}
+
+// HealthCheck runs a query through the pool, unlike a ping it fails when the pool is
+// exhausted until the context is done
+func HealthCheck(ctx context.Context, db *sql.DB) error {
+	var one int
+	return db.QueryRowContext(ctx, `SELECT 1`).Scan(&one)
+}
+
+// DSN postgres connection url
+func (c *DatabaseConfig) DSN() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     fmt.Sprintf("%s:%d", c.Host, c.Port),
+		Path:     c.Name,
+		RawQuery: url.Values{"sslmode": []string{c.SSLMode}}.Encode(),
+	}
+	return u.String()
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Querier is implemented by *sql.DB and *sql.Tx, stores take it to run in or outside a transaction
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+type txKey struct{}
+
+// InTx runs fn in a transaction which is committed when fn returns nil and rolled back on an
+// error or a panic. The transaction is in the context given to fn, InTx calls with that context
+// join it instead of starting another one.
+func InTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) (err error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		return fn(ctx, tx)
+	}
+
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			tx.Rollback()
+			panic(rvr)
+		}
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	return fn(context.WithValue(ctx, txKey{}, tx), tx)
+}
+
+// TxFromContext returns the transaction of an enclosing InTx
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*sql.Tx)
+	return tx, ok
+}
+
+// Conn returns the transaction of an enclosing InTx, the pool otherwise
+func Conn(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return db
+}
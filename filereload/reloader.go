@@ -0,0 +1,124 @@
+package filereload
+
+import (
+	"context"
+	"lake-go/tenant"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader holds a value loaded from files and loads it again when their modification time
+// changes, so rotated keys and renewed certificates are picked up without a restart. The
+// files are checked at most once per interval, on access.
+type Reloader[T any] struct {
+	name     string
+	interval time.Duration
+	modTime  func() (time.Time, error)
+	load     func() (T, error)
+
+	mutex     sync.RWMutex
+	value     T
+	loadedAt  time.Time
+	lastCheck time.Time
+}
+
+// New loads the value once, the error of the first load is returned. modTime reports the
+// latest modification time of the source, see ModTime and DirModTime.
+func New[T any](ctx context.Context, name string, interval time.Duration, modTime func() (time.Time, error), load func() (T, error)) (*Reloader[T], error) {
+	r := &Reloader[T]{
+		name:     name,
+		interval: interval,
+		modTime:  modTime,
+		load:     load,
+	}
+	if err := r.reload(ctx, true); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Get returns the current value, reloading it first when the interval passed and the source changed
+func (r *Reloader[T]) Get(ctx context.Context) T {
+	r.mutex.RLock()
+	due := time.Since(r.lastCheck) >= r.interval
+	r.mutex.RUnlock()
+	if due {
+		if err := r.reload(ctx, false); err != nil {
+			// keep serving the previous value, the new files may be half written
+			tenant.GetLogger(ctx, "Reloader").Errore(ctx, "reload "+r.name+" found error", err)
+		}
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.value
+}
+
+func (r *Reloader[T]) reload(ctx context.Context, force bool) error {
+	r.mutex.Lock()
+	r.lastCheck = time.Now()
+	r.mutex.Unlock()
+
+	modTime, err := r.modTime()
+	if err != nil {
+		return err
+	}
+	r.mutex.RLock()
+	unchanged := modTime.Equal(r.loadedAt)
+	r.mutex.RUnlock()
+	if unchanged && !force {
+		return nil
+	}
+
+	value, err := r.load()
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	r.value = value
+	r.loadedAt = modTime
+	r.mutex.Unlock()
+
+	tenant.GetLogger(ctx, "Reloader").Infow(ctx, r.name+" loaded")
+	return nil
+}
+
+// ModTime returns the latest modification time of the files
+func ModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// DirModTime returns the latest modification time of the directory and its entries,
+// entries removed while reading are skipped
+func DirModTime(dir string) (time.Time, error) {
+	latest, err := ModTime(dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -5,6 +5,7 @@ import (
 	lapm "github.com/tyeryan/l-common-util/apm"
 	ctxutil "github.com/tyeryan/l-protocol/context"
 	"go.elastic.co/apm"
+	"lake-go/tenant"
 	"net/http"
 )
 
@@ -34,7 +35,9 @@ func (f *AccessLogFilter) Filter() func(next http.Handler) http.Handler {
 				if userID, ok := ctxutil.Read(r.Context(), UserReferenceID); ok {
 					tx := apm.TransactionFromContext(r.Context())
 					tx.Context.SetUserID(userID)
-					tx.Context.SetLabel("api-server", "c-user-api")
+					if tenantID, ok := tenant.FromContext(r.Context()); ok {
+						tx.Context.SetLabel("tenant", tenantID)
+					}
 				}
 			}
 			next.ServeHTTP(w, r)
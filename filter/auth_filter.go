@@ -2,9 +2,28 @@ package filter
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis"
 	"github.com/google/wire"
 	"github.com/tyeryan/l-common-util/cache"
+	"github.com/tyeryan/l-common-util/config"
+	ctxutil "github.com/tyeryan/l-protocol/context"
+	logutil "github.com/tyeryan/l-protocol/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"lake-go/apikey"
 	"lake-go/grpcclient"
+	"lake-go/responder"
+	"lake-go/session"
+	"lake-go/tenant"
+	"lake-go/tracing"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	pb "github.com/tyeryan/l-protocol/go/lauth"
 )
@@ -12,21 +31,335 @@ import (
 var (
 	AuthFilterWireSet = wire.NewSet(
 		grpcclient.ProvideLAuthClient,
+		grpcclient.ProvideLAuthConn,
 		grpcclient.ProvideLAuthConfig,
+		ProvideAuthFilterConfig,
 		ProvideAuthFilter,
 	)
 )
 
+const (
+	// UserRoles comma separated roles of the authenticated user
+	UserRoles ctxutil.ContextKey = "x-user-roles"
+	// SessionID session of the bearer token, see session.IDFromToken
+	SessionID ctxutil.ContextKey = "x-session-id"
+	// APIKeyID id of the api key when the request authenticated with X-Api-Key
+	APIKeyID ctxutil.ContextKey = "x-api-key-id"
+	// PrincipalTenant tenant of the credential, unlike tenant.ID it never comes from the request
+	PrincipalTenant ctxutil.ContextKey = "x-principal-tenant"
+
+	AuthModeLAuth = "lauth"
+	AuthModeJWT   = "jwt"
+
+	authorizationHeader = "Authorization"
+	apiKeyHeader        = "X-Api-Key"
+	bearerPrefix        = "Bearer "
+	tokenCacheKeyPrefix = "lake-go:auth-token:"
+)
+
+var (
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrNoCredentials = errors.New("no credentials")
+)
+
+type AuthFilterConfig struct {
+	// Mode selects how bearer tokens are verified, "lauth" asks l-auth, "jwt" verifies signed tokens locally
+	Mode                      string `configdefault:"lauth" configstruct:"AUTH_FILTER_CONFIG_MODE" configvalidate:"required"`
+	ValidTokenCacheTTLInSec   int32  `configdefault:"300" configstruct:"AUTH_FILTER_CONFIG_VALID_TOKEN_CACHE_TTL_IN_SEC" configvalidate:"duration=0s.."`
+	InvalidTokenCacheTTLInSec int32  `configdefault:"30" configstruct:"AUTH_FILTER_CONFIG_INVALID_TOKEN_CACHE_TTL_IN_SEC" configvalidate:"duration=0s.."`
+
+	JWTJWKSFile               string `configstruct:"AUTH_FILTER_CONFIG_JWT_JWKS_FILE"`
+	JWTPEMDir                 string `configstruct:"AUTH_FILTER_CONFIG_JWT_PEM_DIR"`
+	JWTIssuer                 string `configstruct:"AUTH_FILTER_CONFIG_JWT_ISSUER"`
+	JWTAudience               string `configstruct:"AUTH_FILTER_CONFIG_JWT_AUDIENCE"`
+	JWTRolesClaim             string `configdefault:"roles" configstruct:"AUTH_FILTER_CONFIG_JWT_ROLES_CLAIM"`
+	JWTTenantClaim            string `configdefault:"tenant" configstruct:"AUTH_FILTER_CONFIG_JWT_TENANT_CLAIM"`
+	JWTLeewayInSec            int32  `configdefault:"30" configstruct:"AUTH_FILTER_CONFIG_JWT_LEEWAY_IN_SEC" configvalidate:"duration=0s..5m"`
+	JWTKeyReloadIntervalInSec int32  `configdefault:"30" configstruct:"AUTH_FILTER_CONFIG_JWT_KEY_RELOAD_INTERVAL_IN_SEC" configvalidate:"duration=1s.."`
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	UserID    string
+	Roles     []string
+	SessionID string
+	APIKeyID  string
+	// Tenant the token is bound to, empty when the token is not tenant scoped
+	Tenant string
+}
+
+// tokenVerifier resolves a bearer token to its principal, ErrInvalidToken is returned
+// for tokens which are rejected, any other error means the token could not be checked.
+type tokenVerifier interface {
+	verify(ctx context.Context, token string) (*Principal, error)
+}
+
 type AuthFilter struct {
-	client      pb.LAuthClient
-	cacheClient cache.DistributedCache
+	verifier     tokenVerifier
+	sessionStore *session.Store
+	apiKeyStore  *apikey.Store
+}
+
+func ProvideAuthFilterConfig(ctx context.Context, configStore config.ConfigStore) (*AuthFilterConfig, error) {
+	cnf := AuthFilterConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
 }
 
 func ProvideAuthFilter(ctx context.Context,
+	cnf *AuthFilterConfig,
 	client pb.LAuthClient,
-	cacheClient cache.DistributedCache) (*AuthFilter, error) {
+	cacheClient cache.DistributedCache,
+	sessionStore *session.Store,
+	apiKeyStore *apikey.Store) (*AuthFilter, error) {
+	log := logutil.GetLogger("ProvideAuthFilter")
+
+	mode := cnf.Mode
+	if mode == AuthModeJWT && cnf.JWTJWKSFile == "" && cnf.JWTPEMDir == "" {
+		// without keys no token could pass, keep the service up and let l-auth decide
+		log.Warnw(ctx, "jwt mode has neither a jwks file nor a pem directory, falling back to lauth")
+		mode = AuthModeLAuth
+	}
+
+	var verifier tokenVerifier
+	switch mode {
+	case AuthModeLAuth:
+		verifier = &lAuthVerifier{
+			cnf:         cnf,
+			client:      client,
+			cacheClient: cacheClient,
+		}
+	case AuthModeJWT:
+		jwtVerifier, err := newJWTVerifier(ctx, cnf)
+		if err != nil {
+			log.Errore(ctx, "create jwt verifier found error", err)
+			return nil, err
+		}
+		verifier = jwtVerifier
+	default:
+		return nil, fmt.Errorf("unknown auth filter mode %q", cnf.Mode)
+	}
+
+	log.Infow(ctx, "auth filter configured", "mode", mode)
 	return &AuthFilter{
-		client:      client,
-		cacheClient: cacheClient,
+		verifier:     verifier,
+		sessionStore: sessionStore,
+		apiKeyStore:  apiKeyStore,
+	}, nil
+}
+
+// Filter rejects requests without a valid bearer token or api key and puts the user of the
+// credential into the context under UserReferenceID and ctxutil.UserID, and its roles under UserRoles.
+func (f *AuthFilter) Filter() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			log := tenant.GetLogger(ctx, "AuthFilter")
+
+			principal, err := f.Authenticate(ctx, r.Header.Get(apiKeyHeader), r.Header.Get(authorizationHeader))
+			if err == ErrNoCredentials {
+				unauthorized(w, r, "missing credentials")
+				return
+			}
+			if err == ErrInvalidToken {
+				unauthorized(w, r, "invalid credentials")
+				return
+			}
+			if err != nil {
+				log.Errore(ctx, "verify token found error", err)
+				responder.Error(w, r, responder.NewProblem(http.StatusServiceUnavailable, responder.ErrCodeUnavailable, "credentials can't be verified right now"))
+				return
+			}
+
+			ctx, err = WithPrincipal(ctx, principal)
+			if err != nil {
+				log.Warnw(ctx, "token used outside of its tenant", "tokenTenant", principal.Tenant)
+				responder.Error(w, r, responder.NewProblem(http.StatusForbidden, responder.ErrCodeForbidden, "credentials are not valid in this tenant"))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Authenticate resolves the api key or, without one, the bearer token of the authorization
+// value to the principal. It is shared by the http filter and the grpc interceptors.
+func (f *AuthFilter) Authenticate(ctx context.Context, apiKey string, authorization string) (*Principal, error) {
+	if apiKey != "" {
+		return f.verifyAPIKey(ctx, apiKey)
+	}
+	token, ok := ParseBearer(authorization)
+	if !ok {
+		return nil, ErrNoCredentials
+	}
+	return f.Verify(ctx, token)
+}
+
+// WithPrincipal puts the principal into the context under UserReferenceID, ctxutil.UserID, SessionID,
+// APIKeyID, PrincipalTenant and UserRoles. tenant.ErrCrossTenant is returned when the request is scoped to another
+// tenant than the one of the credential, a credential without tenant can't be used in any tenant.
+func WithPrincipal(ctx context.Context, principal *Principal) (context.Context, error) {
+	if _, ok := tenant.FromContext(ctx); ok {
+		if err := tenant.Authorize(ctx, principal.Tenant); err != nil {
+			return ctx, err
+		}
+	}
+	if principal.Tenant != "" {
+		ctx = tenant.WithTenant(ctx, principal.Tenant)
+		ctx = ctxutil.Add(ctx, PrincipalTenant, principal.Tenant)
+	}
+
+	ctx = ctxutil.Add(ctx, UserReferenceID, principal.UserID)
+	ctx = ctxutil.Add(ctx, ctxutil.UserID, principal.UserID)
+	if principal.SessionID != "" {
+		ctx = ctxutil.Add(ctx, SessionID, principal.SessionID)
+	}
+	if principal.APIKeyID != "" {
+		ctx = ctxutil.Add(ctx, APIKeyID, principal.APIKeyID)
+	}
+	if len(principal.Roles) > 0 {
+		ctx = ctxutil.Add(ctx, UserRoles, strings.Join(principal.Roles, ","))
+	}
+	return ctx, nil
+}
+
+// Verify resolves the token to its principal, revoked tokens are rejected with ErrInvalidToken
+func (f *AuthFilter) Verify(ctx context.Context, token string) (*Principal, error) {
+	principal, err := f.verifier.verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := f.sessionStore.IsRevoked(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrInvalidToken
+	}
+	principal.SessionID = session.IDFromToken(token)
+	return principal, nil
+}
+
+// verifyAPIKey resolves an api key to its owner, the scopes of the key are its roles
+func (f *AuthFilter) verifyAPIKey(ctx context.Context, raw string) (*Principal, error) {
+	key, err := f.apiKeyStore.Verify(ctx, raw)
+	if err == apikey.ErrInvalidKey {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{
+		UserID:   key.OwnerID,
+		Roles:    key.Scopes,
+		APIKeyID: strconv.FormatInt(key.ID, 10),
+		Tenant:   key.Tenant,
 	}, nil
 }
+
+// TenantFromContext returns the tenant of the credential AuthFilter put into the context
+func TenantFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctxutil.Read(ctx, PrincipalTenant)
+	return id, ok && id != ""
+}
+
+// RolesFromContext returns the roles AuthFilter put into the context
+func RolesFromContext(ctx context.Context) []string {
+	roles, ok := ctxutil.Read(ctx, UserRoles)
+	if !ok || roles == "" {
+		return nil
+	}
+	return strings.Split(roles, ",")
+}
+
+// lAuthVerifier asks l-auth about every token it has not seen recently, the answer is cached
+// in the distributed cache so both accepted and rejected tokens skip the round trip until they expire.
+type lAuthVerifier struct {
+	cnf         *AuthFilterConfig
+	client      pb.LAuthClient
+	cacheClient cache.DistributedCache
+}
+
+type tokenCacheEntry struct {
+	Valid  bool
+	UserID string
+}
+
+func (v *lAuthVerifier) verify(ctx context.Context, token string) (*Principal, error) {
+	log := tenant.GetLogger(ctx, "lAuthVerifier")
+	key := tokenCacheKey(token)
+
+	entry := &tokenCacheEntry{}
+	err := tracing.Cache(ctx, "get", func() error {
+		return v.cacheClient.Get(key, entry)
+	})
+	if err != nil {
+		if err != redis.Nil {
+			log.Warne(ctx, "read token cache found error", err)
+		}
+
+		entry, err = v.verifyWithLAuth(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+
+		ttl := time.Duration(v.cnf.ValidTokenCacheTTLInSec) * time.Second
+		if !entry.Valid {
+			ttl = time.Duration(v.cnf.InvalidTokenCacheTTLInSec) * time.Second
+		}
+		if err := tracing.Cache(ctx, "set", func() error { return v.cacheClient.Set(key, ttl, entry) }); err != nil {
+			log.Warne(ctx, "write token cache found error", err)
+		}
+	}
+
+	if !entry.Valid {
+		return nil, ErrInvalidToken
+	}
+	return &Principal{UserID: entry.UserID}, nil
+}
+
+// verifyWithLAuth forwards the token to l-auth in the outgoing metadata, l-auth
+// authenticates the call with it and answers with the user reference in the message.
+func (v *lAuthVerifier) verifyWithLAuth(ctx context.Context, token string) (*tokenCacheEntry, error) {
+	ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(authorizationHeader), bearerPrefix+token)
+	rsp, err := v.client.Authenticate(ctx, &pb.AuthReq{})
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unauthenticated, codes.PermissionDenied, codes.InvalidArgument, codes.NotFound:
+			return &tokenCacheEntry{Valid: false}, nil
+		}
+		return nil, err
+	}
+	if rsp.GetMessage() == "" {
+		return &tokenCacheEntry{Valid: false}, nil
+	}
+	return &tokenCacheEntry{Valid: true, UserID: rsp.GetMessage()}, nil
+}
+
+// BearerToken reads the token from the Authorization header
+func BearerToken(r *http.Request) (string, bool) {
+	return ParseBearer(r.Header.Get(authorizationHeader))
+}
+
+// ParseBearer reads the token from an authorization value, grpc callers send it as metadata
+func ParseBearer(header string) (string, bool) {
+	if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(header[len(bearerPrefix):])
+	return token, token != ""
+}
+
+// tokenCacheKey hashes the token so raw credentials never end up in redis keys.
+func tokenCacheKey(token string) string {
+	return fmt.Sprintf("%s%x", tokenCacheKeyPrefix, sha256.Sum256([]byte(token)))
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="lake-go"`)
+	responder.Error(w, r, responder.NewProblem(http.StatusUnauthorized, responder.ErrCodeUnauthorized, detail))
+}
@@ -0,0 +1,78 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-chi/cors"
+	"github.com/tyeryan/l-common-util/config"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+type CORSConfig struct {
+	// AllowedOrigins "*" allows every origin, an origin may hold one * like https://*.example.com
+	AllowedOrigins   []string `configdefault:"*" configstruct:"CORS_CONFIG_ALLOWED_ORIGINS" configvalidate:"required"`
+	AllowedMethods   []string `configdefault:"GET,POST,DELETE,OPTIONS" configstruct:"CORS_CONFIG_ALLOWED_METHODS"`
+	AllowedHeaders   []string `configdefault:"Accept,Authorization,Content-Type,X-CSRF-Token,X-Goog-AuthUser,X-Request-Id,X-Api-Key,X-Tenant-Id,traceparent" configstruct:"CORS_CONFIG_ALLOWED_HEADERS"`
+	ExposedHeaders   []string `configdefault:"Link,Retry-After,X-Request-Id,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,RateLimit-Policy" configstruct:"CORS_CONFIG_EXPOSED_HEADERS"`
+	AllowCredentials bool     `configdefault:"true" configstruct:"CORS_CONFIG_ALLOW_CREDENTIALS"`
+	// MaxAgeInSec 300 is the maximum value not ignored by any of the major browsers
+	MaxAgeInSec int32 `configdefault:"300" configstruct:"CORS_CONFIG_MAX_AGE_IN_SEC" configvalidate:"duration=0s..24h"`
+}
+
+// CORS answers preflight requests and sets the CORS headers, its options can change at runtime
+type CORS struct {
+	cors atomic.Pointer[cors.Cors]
+}
+
+func ProvideCORSConfig(ctx context.Context, configStore config.ConfigStore) (*CORSConfig, error) {
+	cnf := CORSConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
+}
+
+func ProvideCORS(ctx context.Context, cnf *CORSConfig) (*CORS, error) {
+	c := &CORS{}
+	if err := c.Apply(cnf); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Validate checks the origins, which the configvalidate rules can't
+func (cnf *CORSConfig) Validate() error {
+	for _, origin := range cnf.AllowedOrigins {
+		if origin != "*" && (!strings.Contains(origin, "://") || strings.Count(origin, "*") > 1) {
+			return fmt.Errorf("invalid cors origin %q", origin)
+		}
+	}
+	return nil
+}
+
+// Apply switches to the options of cnf, requests already in flight finish with the previous ones
+func (c *CORS) Apply(cnf *CORSConfig) error {
+	if err := cnf.Validate(); err != nil {
+		return err
+	}
+	c.cors.Store(cors.New(cors.Options{
+		AllowedOrigins:   cnf.AllowedOrigins,
+		AllowedMethods:   cnf.AllowedMethods,
+		AllowedHeaders:   cnf.AllowedHeaders,
+		ExposedHeaders:   cnf.ExposedHeaders,
+		AllowCredentials: cnf.AllowCredentials,
+		MaxAge:           int(cnf.MaxAgeInSec),
+	}))
+	return nil
+}
+
+func (c *CORS) Filter() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			c.cors.Load().Handler(next).ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
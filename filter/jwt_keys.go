@@ -0,0 +1,208 @@
+package filter
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"lake-go/filereload"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// jwk is a single entry of a JWKS document (RFC 7517), only the fields we need
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type verificationKey struct {
+	kid string
+	// key is *rsa.PublicKey, *ecdsa.PublicKey or []byte for hmac secrets
+	key crypto.PublicKey
+}
+
+// keySet holds the keys loaded from a JWKS file or a PEM directory, rotated keys are picked
+// up without a restart
+type keySet struct {
+	keys *filereload.Reloader[[]verificationKey]
+}
+
+func newKeySet(ctx context.Context, jwksFile string, pemDir string, reloadInterval time.Duration) (*keySet, error) {
+	if jwksFile == "" && pemDir == "" {
+		return nil, errors.New("jwt verification requires a jwks file or a pem directory")
+	}
+	modTime := func() (time.Time, error) { return filereload.DirModTime(pemDir) }
+	load := func() ([]verificationKey, error) { return loadPEMDir(pemDir) }
+	if jwksFile != "" {
+		modTime = func() (time.Time, error) { return filereload.ModTime(jwksFile) }
+		load = func() ([]verificationKey, error) { return loadJWKSFile(jwksFile) }
+	}
+
+	keys, err := filereload.New(ctx, "jwt keys", reloadInterval, modTime, func() ([]verificationKey, error) {
+		keys, err := load()
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			return nil, errors.New("no jwt verification key found")
+		}
+		return keys, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &keySet{keys: keys}, nil
+}
+
+// lookup returns the candidate keys for kid, all keys are candidates when the token has no kid
+func (ks *keySet) lookup(ctx context.Context, kid string) []verificationKey {
+	keys := ks.keys.Get(ctx)
+	if kid == "" {
+		return keys
+	}
+	var found []verificationKey
+	for _, k := range keys {
+		if k.kid == kid {
+			found = append(found, k)
+		}
+	}
+	return found
+}
+
+func loadJWKSFile(path string) ([]verificationKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set jwks
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("parse jwks file %s: %w", path, err)
+	}
+
+	var keys []verificationKey
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("parse jwk %q: %w", k.Kid, err)
+		}
+		keys = append(keys, verificationKey{kid: k.Kid, key: key})
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, err
+		}
+		return secret, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+// loadPEMDir loads every .pem file of the directory, the file name without extension is the kid
+func loadPEMDir(dir string) ([]verificationKey, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []verificationKey
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".pem" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode(raw)
+		if block == nil {
+			return nil, fmt.Errorf("no pem block found in %s", path)
+		}
+		key, err := parsePEMBlock(block)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		keys = append(keys, verificationKey{kid: strings.TrimSuffix(entry.Name(), ".pem"), key: key})
+	}
+	return keys, nil
+}
+
+func parsePEMBlock(block *pem.Block) (crypto.PublicKey, error) {
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported pem block %s", block.Type)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
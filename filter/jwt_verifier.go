@@ -0,0 +1,177 @@
+package filter
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// jwtVerifier validates signed tokens locally against a keySet, without calling l-auth
+type jwtVerifier struct {
+	keys        *keySet
+	issuer      string
+	audience    string
+	rolesClaim  string
+	tenantClaim string
+	leeway      time.Duration
+}
+
+func newJWTVerifier(ctx context.Context, cnf *AuthFilterConfig) (*jwtVerifier, error) {
+	keys, err := newKeySet(ctx, cnf.JWTJWKSFile, cnf.JWTPEMDir, time.Duration(cnf.JWTKeyReloadIntervalInSec)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &jwtVerifier{
+		keys:        keys,
+		issuer:      cnf.JWTIssuer,
+		audience:    cnf.JWTAudience,
+		rolesClaim:  cnf.JWTRolesClaim,
+		tenantClaim: cnf.JWTTenantClaim,
+		leeway:      time.Duration(cnf.JWTLeewayInSec) * time.Second,
+	}, nil
+}
+
+func (v *jwtVerifier) verify(ctx context.Context, token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, k := range v.keys.lookup(ctx, header.Kid) {
+		if verifySignature(header.Alg, k.key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrInvalidToken
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, ErrInvalidToken
+	}
+	tenantID, _ := claims[v.tenantClaim].(string)
+	return &Principal{
+		UserID: subject,
+		Roles:  stringsClaim(claims[v.rolesClaim]),
+		Tenant: tenantID,
+	}, nil
+}
+
+func (v *jwtVerifier) validateClaims(claims map[string]interface{}) error {
+	now := time.Now()
+
+	exp, ok := numericClaim(claims["exp"])
+	if !ok {
+		return fmt.Errorf("missing exp claim")
+	}
+	if now.After(time.Unix(exp, 0).Add(v.leeway)) {
+		return fmt.Errorf("token expired")
+	}
+	if nbf, ok := numericClaim(claims["nbf"]); ok && now.Add(v.leeway).Before(time.Unix(nbf, 0)) {
+		return fmt.Errorf("token not yet valid")
+	}
+	if v.issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.issuer {
+			return fmt.Errorf("unexpected issuer %q", iss)
+		}
+	}
+	if v.audience != "" && !slices.Contains(stringsClaim(claims["aud"]), v.audience) {
+		return fmt.Errorf("audience %q not allowed", v.audience)
+	}
+	return nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed []byte, signature []byte) bool {
+	digest := sha256.Sum256(signed)
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return false
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+	default:
+		// "none" and anything we don't know is rejected
+		return false
+	}
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func numericClaim(v interface{}) (int64, bool) {
+	f, ok := v.(float64)
+	return int64(f), ok
+}
+
+// stringsClaim accepts a single string, a space separated string (oauth scope style) or a string array
+func stringsClaim(v interface{}) []string {
+	switch val := v.(type) {
+	case string:
+		return strings.Fields(val)
+	case []interface{}:
+		var values []string
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
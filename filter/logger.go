@@ -2,18 +2,96 @@ package filter
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"github.com/go-chi/chi/middleware"
-	logutil "github.com/tyeryan/l-protocol/log"
-	"io/ioutil"
+	"github.com/tyeryan/l-common-util/config"
+	"io"
+	"lake-go/tenant"
+	"math/rand"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
-// RequestResponseLogger returns a logger handler which logs http request and response.
-func RequestResponseLogger() func(next http.Handler) http.Handler {
+type RequestLoggerConfig struct {
+	// MaxBodyBytes caps the captured request and response body, json bodies beyond it are not
+	// logged at all as they can't be redacted
+	MaxBodyBytes int32 `configdefault:"4096" configstruct:"REQUEST_LOGGER_CONFIG_MAX_BODY_BYTES" configvalidate:"min=0"`
+	// ContentTypes bodies of other media types are never logged
+	ContentTypes []string `configdefault:"application/json,application/problem+json,application/x-www-form-urlencoded,text/plain" configstruct:"REQUEST_LOGGER_CONFIG_CONTENT_TYPES"`
+	// RedactFields a bare name redacts the field at any depth, a dotted path like $.user.password
+	// only the field at that path, both case insensitive
+	RedactFields  []string `configdefault:"password,token,accessToken,refreshToken,secret,key" configstruct:"REQUEST_LOGGER_CONFIG_REDACT_FIELDS"`
+	RedactHeaders []string `configdefault:"Authorization,Cookie,Set-Cookie,X-Api-Key" configstruct:"REQUEST_LOGGER_CONFIG_REDACT_HEADERS"`
+	// SampleRoutes path=ratio pairs, a trailing * matches a prefix. Requests to a path are logged
+	// with the given probability, 0 skips the path.
+	SampleRoutes []string `configdefault:"/v1/healthcheck=0,/v1/livez=0,/v1/readyz=0,/v1/docs/*=0.01" configstruct:"REQUEST_LOGGER_CONFIG_SAMPLE_ROUTES"`
+}
+
+// RequestLogger logs every request and its response as a canonical line, with the bodies
+// captured up to a limit and the sensitive fields and headers redacted
+type RequestLogger struct {
+	cnf          *RequestLoggerConfig
+	contentTypes map[string]bool
+	redactor     *redactor
+	headers      map[string]bool
+	samples      []*routeSample
+}
+
+type routeSample struct {
+	path   string
+	prefix bool
+	ratio  float64
+}
+
+func ProvideRequestLoggerConfig(ctx context.Context, configStore config.ConfigStore) (*RequestLoggerConfig, error) {
+	cnf := RequestLoggerConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
+}
+
+func ProvideRequestLogger(ctx context.Context, cnf *RequestLoggerConfig) (*RequestLogger, error) {
+	l := &RequestLogger{
+		cnf:          cnf,
+		contentTypes: map[string]bool{},
+		redactor:     newRedactor(cnf.RedactFields),
+		headers:      map[string]bool{},
+	}
+	for _, contentType := range cnf.ContentTypes {
+		l.contentTypes[strings.ToLower(strings.TrimSpace(contentType))] = true
+	}
+	for _, header := range cnf.RedactHeaders {
+		l.headers[http.CanonicalHeaderKey(strings.TrimSpace(header))] = true
+	}
+	for _, sample := range cnf.SampleRoutes {
+		path, rawRatio, ok := strings.Cut(sample, "=")
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(rawRatio), 64)
+		if !ok || err != nil || ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("invalid sample route %q, expected path=ratio with a ratio between 0 and 1", sample)
+		}
+		path = strings.TrimSpace(path)
+		l.samples = append(l.samples, &routeSample{
+			path:   strings.TrimSuffix(path, "*"),
+			prefix: strings.HasSuffix(path, "*"),
+			ratio:  ratio,
+		})
+	}
+	return l, nil
+}
+
+// Filter logs the request before it is served and the canonical line with the response after
+func (l *RequestLogger) Filter() func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
-			log := logutil.GetLogger("httpLogger")
+			if !l.sampled(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			log := tenant.GetLogger(r.Context(), "httpLogger")
 
 			log.Add("method", r.Method)
 			log.Add("remote", r.RemoteAddr)
@@ -24,26 +102,30 @@ func RequestResponseLogger() func(next http.Handler) http.Handler {
 				scheme = "https"
 			}
 			log.Add("path", fmt.Sprintf("%s://%s%s", scheme, r.Host, r.RequestURI))
+			log.Add("reqHeaders", l.redactHeaders(r.Header))
 
-			if r.GetBody != nil {
-				body, err := r.GetBody()
+			if l.loggable(r.Header.Get("Content-Type")) && r.Body != nil && r.Body != http.NoBody {
+				// read one byte more than the cap to tell a full body from a truncated one, the
+				// handler gets the captured part followed by the rest of the original body
+				captured, err := io.ReadAll(io.LimitReader(r.Body, int64(l.cnf.MaxBodyBytes)+1))
+				r.Body = &replayBody{Reader: io.MultiReader(bytes.NewReader(captured), r.Body), Closer: r.Body}
 				if err == nil {
-					reqBody, err := ioutil.ReadAll(body)
-					if err == nil {
-						log.Add("reqBody", string(reqBody))
-					}
+					l.addBody(log.Add, "reqBody", r.Header.Get("Content-Type"), captured)
 				}
 			}
 
 			log.Infow(r.Context(), "http request")
 
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
-			var rspWriter bytes.Buffer
-			ww.Tee(&rspWriter)
+			rspBody := &limitedBuffer{limit: int(l.cnf.MaxBodyBytes) + 1}
+			ww.Tee(rspBody)
 
 			defer func() {
 				log.Add("httpStatus", ww.Status())
-				log.Add("rspBody", rspWriter)
+				log.Add("rspHeaders", l.redactHeaders(ww.Header()))
+				if contentType := ww.Header().Get("Content-Type"); l.loggable(contentType) {
+					l.addBody(log.Add, "rspBody", contentType, rspBody.Bytes())
+				}
 				log.Canonical(r.Context(), "http response", nil, recover())
 			}()
 
@@ -52,3 +134,77 @@ func RequestResponseLogger() func(next http.Handler) http.Handler {
 		return http.HandlerFunc(fn)
 	}
 }
+
+func (l *RequestLogger) sampled(path string) bool {
+	for _, s := range l.samples {
+		if path == s.path || (s.prefix && strings.HasPrefix(path, s.path)) {
+			return s.ratio >= 1 || (s.ratio > 0 && rand.Float64() < s.ratio)
+		}
+	}
+	return true
+}
+
+func (l *RequestLogger) loggable(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && l.contentTypes[mediaType]
+}
+
+// addBody adds the redacted body under key, bodies which can't be redacted are replaced by their size
+func (l *RequestLogger) addBody(add func(keysAndValues ...interface{}), key string, contentType string, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	truncated := len(body) > int(l.cnf.MaxBodyBytes)
+	if truncated {
+		body = body[:l.cnf.MaxBodyBytes]
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	redacted, ok := l.redactor.redact(mediaType, body, truncated)
+	if !ok {
+		size := fmt.Sprintf("%d bytes", len(body))
+		if truncated {
+			size = "more than " + size
+		}
+		add(key+"Omitted", fmt.Sprintf("%s of %s which can't be redacted", size, mediaType))
+		return
+	}
+	add(key, redacted)
+	if truncated {
+		add(key+"Truncated", true)
+	}
+}
+
+func (l *RequestLogger) redactHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		if l.headers[name] {
+			headers[name] = redacted
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+	return headers
+}
+
+// replayBody serves the captured start of a request body before the rest, closing the original
+type replayBody struct {
+	io.Reader
+	io.Closer
+}
+
+// limitedBuffer keeps the first limit bytes written to it and drops the rest
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,45 @@
+package filter
+
+import (
+	"github.com/go-chi/chi/v5"
+	"lake-go/policy"
+	"lake-go/responder"
+	"lake-go/tenant"
+	"net/http"
+)
+
+func ProvidePolicyFilter(engine *policy.Engine) *PolicyFilter {
+	return &PolicyFilter{
+		engine: engine,
+	}
+}
+
+type PolicyFilter struct {
+	engine *policy.Engine
+}
+
+// Be aware to include this filter after access_token filter, it authorizes the roles
+// AuthFilter put into the context against the chi route pattern of the request.
+func (f *PolicyFilter) Filter() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			route := chi.RouteContext(ctx).RoutePattern()
+			roles := RolesFromContext(ctx)
+
+			decision := f.engine.Evaluate(r.Method, route, roles)
+			if !decision.Allowed {
+				ruleName := ""
+				if decision.Rule != nil {
+					ruleName = decision.Rule.Name
+				}
+				tenant.GetLogger(ctx, "PolicyFilter").Warnw(ctx, "request denied by policy",
+					"method", r.Method, "route", route, "roles", roles, "rule", ruleName, "reason", decision.Reason)
+				responder.Error(w, r, responder.NewProblem(http.StatusForbidden, responder.ErrCodeForbidden, "access to the route is denied"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
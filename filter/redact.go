@@ -0,0 +1,110 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// redactor replaces sensitive fields of logged bodies
+type redactor struct {
+	// names match a field at any depth
+	names map[string]bool
+	// paths match a field at a dotted path from the root, arrays are transparent
+	paths map[string]bool
+}
+
+func newRedactor(fields []string) *redactor {
+	r := &redactor{names: map[string]bool{}, paths: map[string]bool{}}
+	for _, field := range fields {
+		field = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(field), "$."))
+		if field == "" {
+			continue
+		}
+		if strings.Contains(field, ".") {
+			r.paths[field] = true
+		} else {
+			r.names[field] = true
+		}
+	}
+	return r
+}
+
+// redact returns the body with the sensitive fields replaced, false when the body can't be
+// redacted safely, like truncated json
+func (r *redactor) redact(mediaType string, body []byte, truncated bool) (string, bool) {
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		return r.redactForm(body, truncated)
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		if truncated {
+			return "", false
+		}
+		return r.redactJSON(body)
+	default:
+		return string(body), true
+	}
+}
+
+func (r *redactor) redactJSON(body []byte) (string, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return "", false
+	}
+	out, err := json.Marshal(r.walk(v, ""))
+	if err != nil {
+		return "", false
+	}
+	return string(out), true
+}
+
+func (r *redactor) walk(v interface{}, path string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, child := range val {
+			childPath := strings.ToLower(key)
+			if path != "" {
+				childPath = path + "." + childPath
+			}
+			if r.names[strings.ToLower(key)] || r.paths[childPath] {
+				val[key] = redacted
+				continue
+			}
+			val[key] = r.walk(child, childPath)
+		}
+		return val
+	case []interface{}:
+		for i, child := range val {
+			val[i] = r.walk(child, path)
+		}
+		return val
+	default:
+		return v
+	}
+}
+
+// redactForm a truncated form loses at most its last value, the keys before it are complete
+func (r *redactor) redactForm(body []byte, truncated bool) (string, bool) {
+	if truncated {
+		if i := bytes.LastIndexByte(body, '&'); i >= 0 {
+			body = body[:i]
+		} else {
+			return "", false
+		}
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return "", false
+	}
+	for key := range values {
+		if r.names[strings.ToLower(key)] || r.paths[strings.ToLower(key)] {
+			values[key] = []string{redacted}
+		}
+	}
+	return values.Encode(), true
+}
@@ -0,0 +1,95 @@
+package filter
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	ctxutil "github.com/tyeryan/l-protocol/context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	RequestIDHeader   = "X-Request-Id"
+	traceparentHeader = "traceparent"
+
+	maxRequestIDLength = 128
+	// crockford base32, the alphabet of ULIDs
+	ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// RequestID seeds ctxutil.Stan from X-Request-Id, the trace id of a W3C traceparent or a new ULID.
+// The stan is logged with every line, forwarded to l-auth in the grpc metadata and echoed back
+// in X-Request-Id, so a request can be followed across services.
+func RequestID() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			stan := ResolveRequestID(r.Header.Get(RequestIDHeader), r.Header.Get(traceparentHeader))
+			w.Header().Set(RequestIDHeader, stan)
+			next.ServeHTTP(w, r.WithContext(ctxutil.Add(r.Context(), ctxutil.Stan, stan)))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// ResolveRequestID picks the request id of a call, a ULID is generated when the caller sent none
+func ResolveRequestID(requestID string, traceparent string) string {
+	if id := validRequestID(requestID); id != "" {
+		return id
+	}
+	if id := traceIDFromTraceparent(traceparent); id != "" {
+		return id
+	}
+	return newULID(time.Now())
+}
+
+// validRequestID rejects ids which would be unsafe to log or to send as grpc metadata
+func validRequestID(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" || len(id) > maxRequestIDLength {
+		return ""
+	}
+	for _, c := range id {
+		isAlnum := (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !isAlnum && !strings.ContainsRune("-_.:/+=", c) {
+			return ""
+		}
+	}
+	return id
+}
+
+// traceIDFromTraceparent returns the trace id of a version-traceid-parentid-flags traceparent header
+func traceIDFromTraceparent(traceparent string) string {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return ""
+	}
+	traceID := strings.ToLower(parts[1])
+	if len(traceID) != 32 || strings.Trim(traceID, "0") == "" {
+		return ""
+	}
+	for _, c := range traceID {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return ""
+		}
+	}
+	return traceID
+}
+
+// newULID builds a ULID, 48 bits of milliseconds followed by 80 random bits, so ids sort by time
+func newULID(now time.Time) string {
+	var raw [16]byte
+	binary.BigEndian.PutUint64(raw[:8], uint64(now.UnixMilli())<<16)
+	_, _ = rand.Read(raw[6:])
+
+	// 128 bits in 26 base32 characters, the first character only carries 3 bits
+	var out [26]byte
+	hi := binary.BigEndian.Uint64(raw[:8])
+	lo := binary.BigEndian.Uint64(raw[8:])
+	for i := 25; i >= 0; i-- {
+		out[i] = ulidAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
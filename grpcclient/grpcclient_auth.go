@@ -2,17 +2,39 @@ package grpcclient
 
 import (
 	"context"
+	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
 	config "github.com/tyeryan/l-common-util/config"
-	grpcclient "github.com/tyeryan/l-common-util/grpcclient"
 	"github.com/tyeryan/l-protocol/go/lauth"
 	. "github.com/tyeryan/l-protocol/log"
+	"go.elastic.co/apm/module/apmgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"lake-go/metrics"
+	"lake-go/tracing"
+	"sync/atomic"
 	"time"
 )
 
+// messageSizeLimit of calls in both directions, as l-common-util sets it
+const messageSizeLimit = 30 * 1024 * 1024
+
 type LAuthConfig struct {
-	LAuthAddr              string `configstruct:"GRPC_CLIENT_CONFIG_L_AUTH"`
-	LAuthTimeoutInSec      int32  `configdefault:"30" configstruct:"GRPC_CLIENT_CONFIG_L_AUTH_TIMEOUT_IN_SEC,omitempty"`
-	LAuthRetryBackoffInSec int32  `configdefault:"1" configstruct:"GRPC_CLIENT_CONFIG_L_AUTH_RETRY_BACKOFF_IN_SEC,omitempty"`
+	LAuthAddr string `configstruct:"GRPC_CLIENT_CONFIG_L_AUTH" configvalidate:"required,hostport"`
+	// LAuthTimeoutInSec bounds every call without a shorter deadline, unlike the address and the
+	// backoff it is applied on a config reload
+	LAuthTimeoutInSec      int32 `configdefault:"30" configstruct:"GRPC_CLIENT_CONFIG_L_AUTH_TIMEOUT_IN_SEC,omitempty" configvalidate:"duration=1s..5m"`
+	LAuthRetryBackoffInSec int32 `configdefault:"1" configstruct:"GRPC_CLIENT_CONFIG_L_AUTH_RETRY_BACKOFF_IN_SEC,omitempty" configvalidate:"duration=0s..1m"`
+}
+
+// LAuthConn connection to l-auth, kept apart from the client so its state can be health checked
+type LAuthConn struct {
+	*grpc.ClientConn
+}
+
+// LAuthTimeout is the current call timeout of l-auth
+type LAuthTimeout struct {
+	timeout atomic.Int64
 }
 
 func ProvideLAuthConfig(ctx context.Context, configStore config.ConfigStore) (*LAuthConfig, error) {
@@ -23,12 +45,60 @@ func ProvideLAuthConfig(ctx context.Context, configStore config.ConfigStore) (*L
 	return &cnf, nil
 }
 
-func ProvideLAuthClient(ctx context.Context, cnf *LAuthConfig) (lauth.LAuthClient, error) {
-	log := GetLogger("ProvideCAuthClient")
-	conn, err := grpcclient.NewGRPCConnection(cnf.LAuthAddr, grpcclient.WithTimeout(time.Duration(cnf.LAuthTimeoutInSec)), grpcclient.WithRetryBackoff(time.Duration(cnf.LAuthRetryBackoffInSec)))
+// ProvideLAuthConn dials without grpcclient.NewGRPCConnection of l-common-util, its service config
+// isn't valid json so every dial failed, and it took the timeout and the backoff as nanoseconds
+func ProvideLAuthConn(ctx context.Context, cnf *LAuthConfig) (*LAuthConn, error) {
+	log := GetLogger("ProvideLAuthConn")
+	retry := []grpc_retry.CallOption{
+		grpc_retry.WithBackoff(grpc_retry.BackoffLinear(time.Duration(cnf.LAuthRetryBackoffInSec) * time.Second)),
+		grpc_retry.WithCodes(codes.Internal, codes.Unavailable, codes.Unknown, codes.Unimplemented),
+	}
+	conn, err := grpc.Dial(cnf.LAuthAddr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(messageSizeLimit),
+			grpc.MaxCallSendMsgSize(messageSizeLimit)),
+		grpc.WithDefaultServiceConfig(`{"loadBalancingPolicy": "round_robin"}`),
+		grpc.WithStreamInterceptor(grpc_retry.StreamClientInterceptor(retry...)),
+		grpc.WithChainUnaryInterceptor(
+			apmgrpc.NewUnaryClientInterceptor(),
+			grpc_retry.UnaryClientInterceptor(retry...)))
 	if err != nil {
 		log.Errore(ctx, "connect to l-auth service found error", err)
 		return nil, err
 	}
-	return lauth.NewLAuthClient(conn), nil
+	return &LAuthConn{ClientConn: conn}, nil
+}
+
+func ProvideLAuthTimeout(ctx context.Context, cnf *LAuthConfig) *LAuthTimeout {
+	t := &LAuthTimeout{}
+	t.Apply(cnf)
+	return t
+}
+
+func ProvideLAuthClient(ctx context.Context, conn *LAuthConn, clientMetrics *metrics.GRPCClientMetrics, timeout *LAuthTimeout) (lauth.LAuthClient, error) {
+	return lauth.NewLAuthClient(tracing.Instrument(clientMetrics.Instrument(timeout.Instrument(conn)))), nil
+}
+
+func (t *LAuthTimeout) Apply(cnf *LAuthConfig) {
+	t.timeout.Store(int64(time.Duration(cnf.LAuthTimeoutInSec) * time.Second))
+}
+
+// Instrument sets the deadline of calls whose context has none or a later one
+func (t *LAuthTimeout) Instrument(cc grpc.ClientConnInterface) grpc.ClientConnInterface {
+	return &timeoutConn{ClientConnInterface: cc, timeout: t}
+}
+
+type timeoutConn struct {
+	grpc.ClientConnInterface
+	timeout *LAuthTimeout
+}
+
+func (c *timeoutConn) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	if timeout := time.Duration(c.timeout.timeout.Load()); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return c.ClientConnInterface.Invoke(ctx, method, args, reply, opts...)
 }
@@ -0,0 +1,21 @@
+package grpcserver
+
+import (
+	"context"
+	"github.com/tyeryan/l-common-util/config"
+)
+
+type GRPCServerConfig struct {
+	// Enable serves grpc on the http listener, calls are told apart by their content type
+	Enable          bool  `configdefault:"true" configstruct:"GRPC_SERVER_CONFIG_ENABLE"`
+	Reflection      bool  `configdefault:"true" configstruct:"GRPC_SERVER_CONFIG_REFLECTION"`
+	MaxRecvMsgBytes int32 `configdefault:"4194304" configstruct:"GRPC_SERVER_CONFIG_MAX_RECV_MSG_BYTES" configvalidate:"min=1024"`
+}
+
+func ProvideGRPCServerConfig(ctx context.Context, configStore config.ConfigStore) (*GRPCServerConfig, error) {
+	cnf := GRPCServerConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
+}
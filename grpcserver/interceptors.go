@@ -0,0 +1,186 @@
+package grpcserver
+
+import (
+	"context"
+	ctxutil "github.com/tyeryan/l-protocol/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"lake-go/audit"
+	"lake-go/filter"
+	"lake-go/policy"
+	"lake-go/tenant"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+const (
+	requestIDKey     = "x-request-id"
+	traceparentKey   = "traceparent"
+	authorizationKey = "authorization"
+	apiKeyKey        = "x-api-key"
+	userAgentKey     = "user-agent"
+)
+
+// reservedKeys are set by lake-go itself, callers must not be able to smuggle them in as
+// ctxutil.Read falls back to the incoming metadata
+var reservedKeys = []ctxutil.ContextKey{
+	ctxutil.Stan,
+	ctxutil.UserID,
+	filter.UserReferenceID,
+	filter.UserRoles,
+	filter.SessionID,
+	filter.APIKeyID,
+	filter.PrincipalTenant,
+	tenant.ID,
+}
+
+// interceptor runs the same steps for unary and stream calls: recovery, request context,
+// authentication, the authorization policy and the audit of authenticated calls
+type interceptor struct {
+	authFilter     *filter.AuthFilter
+	policyEngine   *policy.Engine
+	tenantResolver *tenant.Resolver
+	recorder       *audit.Recorder
+	// public full method names which don't require credentials
+	public map[string]bool
+}
+
+func (i *interceptor) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (rsp interface{}, err error) {
+	err = i.intercept(ctx, info.FullMethod, func(ctx context.Context) error {
+		rsp, err = handler(ctx, req)
+		return err
+	})
+	return rsp, err
+}
+
+func (i *interceptor) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return i.intercept(ss.Context(), info.FullMethod, func(ctx context.Context) error {
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	})
+}
+
+func (i *interceptor) intercept(ctx context.Context, fullMethod string, call func(ctx context.Context) error) (err error) {
+	start := time.Now()
+	ctx, err = i.requestContext(ctx)
+	if err != nil {
+		return err
+	}
+	log := tenant.GetLogger(ctx, "grpcServer")
+	// authCtx carries the caller once the credentials are verified, only those calls are audited
+	var authCtx context.Context
+
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			log.Errorw(ctx, "grpc handler panicked", "panic", rvr, "stack", string(debug.Stack()))
+			err = status.Error(codes.Internal, "internal error")
+		}
+		log.Infow(ctx, "grpc call", "method", fullMethod, "code", status.Code(err).String(), "durationMs", time.Since(start).Milliseconds())
+		if authCtx != nil {
+			i.recorder.Record(authCtx, &audit.Event{
+				Action:   fullMethod,
+				Resource: fullMethod,
+				Outcome:  outcome(status.Code(err)),
+				Detail:   map[string]string{"code": status.Code(err).String()},
+			})
+		}
+	}()
+
+	if i.public[fullMethod] {
+		return call(ctx)
+	}
+	authCtx, err = i.authenticate(ctx, fullMethod)
+	if err != nil {
+		return err
+	}
+	return call(authCtx)
+}
+
+// requestContext drops reserved keys from the incoming metadata and seeds the stan and the tenant
+func (i *interceptor) requestContext(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+	tenantID := first(md, strings.ToLower(i.tenantResolver.Header()))
+	for _, key := range reservedKeys {
+		md.Delete(string(key))
+	}
+	ctx = metadata.NewIncomingContext(ctx, md)
+
+	stan := filter.ResolveRequestID(first(md, requestIDKey), first(md, traceparentKey))
+	ctx = ctxutil.Add(ctx, ctxutil.Stan, stan)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, stan))
+
+	if tenantID != "" {
+		if !i.tenantResolver.Known(tenantID) {
+			return nil, status.Error(codes.InvalidArgument, "unknown tenant")
+		}
+		ctx = tenant.WithTenant(ctx, tenantID)
+	}
+	return ctx, nil
+}
+
+func (i *interceptor) authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	log := tenant.GetLogger(ctx, "grpcServer")
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	principal, err := i.authFilter.Authenticate(ctx, first(md, apiKeyKey), first(md, authorizationKey))
+	if err == filter.ErrNoCredentials {
+		return nil, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+	if err == filter.ErrInvalidToken {
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+	if err != nil {
+		log.Errore(ctx, "verify token found error", err)
+		return nil, status.Error(codes.Unavailable, "credentials can't be verified right now")
+	}
+	if ctx, err = filter.WithPrincipal(ctx, principal); err != nil {
+		log.Warnw(ctx, "token used outside of its tenant", "tokenTenant", principal.Tenant)
+		return nil, status.Error(codes.PermissionDenied, "credentials are not valid in this tenant")
+	}
+
+	// grpc calls are http posts to their full method name, policy rules match them like routes
+	roles := filter.RolesFromContext(ctx)
+	decision := i.policyEngine.Evaluate(http.MethodPost, fullMethod, roles)
+	if !decision.Allowed {
+		ruleName := ""
+		if decision.Rule != nil {
+			ruleName = decision.Rule.Name
+		}
+		log.Warnw(ctx, "call denied by policy", "method", fullMethod, "roles", roles, "rule", ruleName, "reason", decision.Reason)
+		// the context of the known caller is kept so the denial is audited
+		return ctx, status.Error(codes.PermissionDenied, "access to the method is denied")
+	}
+	return ctx, nil
+}
+
+func outcome(code codes.Code) string {
+	switch code {
+	case codes.OK:
+		return audit.OutcomeSuccess
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return audit.OutcomeDenied
+	default:
+		return audit.OutcomeFailure
+	}
+}
+
+// serverStream carries the intercepted context into stream handlers
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package grpcserver
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"lake-go/policy"
+	pb "lake-go/proto/lake"
+	"lake-go/tenant"
+	"strings"
+)
+
+// LakeAdminServer serves the /v1/admin operations over grpc
+type LakeAdminServer struct {
+	pb.UnimplementedLakeAdminServer
+	policyEngine *policy.Engine
+}
+
+func ProvideLakeAdminServer(ctx context.Context, policyEngine *policy.Engine) (*LakeAdminServer, error) {
+	return &LakeAdminServer{
+		policyEngine: policyEngine,
+	}, nil
+}
+
+// CheckPolicy answers whether a user with the given roles may call a route, grpc methods are checked
+// as POST to their full method name
+func (s *LakeAdminServer) CheckPolicy(ctx context.Context, req *pb.CheckPolicyReq) (*pb.CheckPolicyRsp, error) {
+	log := tenant.GetLogger(ctx, "CheckPolicy")
+
+	method := strings.ToUpper(req.GetMethod())
+	if method == "" || req.GetRoute() == "" {
+		return nil, status.Error(codes.InvalidArgument, "method and route are required")
+	}
+
+	decision := s.policyEngine.Evaluate(method, req.GetRoute(), req.GetRoles())
+	log.Infow(ctx, "CheckPolicy done", "roles", req.GetRoles(), "method", method, "route", req.GetRoute(), "allowed", decision.Allowed)
+
+	rsp := &pb.CheckPolicyRsp{Allowed: decision.Allowed, Reason: decision.Reason}
+	if decision.Rule != nil {
+		rsp.Rule = decision.Rule.Name
+	}
+	return rsp, nil
+}
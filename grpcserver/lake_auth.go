@@ -0,0 +1,229 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	ctxutil "github.com/tyeryan/l-protocol/context"
+	"github.com/tyeryan/l-protocol/go/lauth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"lake-go/apikey"
+	"lake-go/clientip"
+	"lake-go/filter"
+	"lake-go/handler/auth"
+	pb "lake-go/proto/lake"
+	"lake-go/responder"
+	"lake-go/session"
+	"lake-go/tenant"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// LakeAuthServer serves the /v1/auth operations over grpc, on top of the same stores as the http handlers
+type LakeAuthServer struct {
+	pb.UnimplementedLakeAuthServer
+	authHandler  *auth.AuthHandler
+	sessionStore *session.Store
+	apiKeyStore  *apikey.Store
+	clientIP     *clientip.Resolver
+}
+
+func ProvideLakeAuthServer(ctx context.Context,
+	authHandler *auth.AuthHandler,
+	sessionStore *session.Store,
+	apiKeyStore *apikey.Store,
+	clientIP *clientip.Resolver) (*LakeAuthServer, error) {
+	return &LakeAuthServer{
+		authHandler:  authHandler,
+		sessionStore: sessionStore,
+		apiKeyStore:  apiKeyStore,
+		clientIP:     clientIP,
+	}, nil
+}
+
+func (s *LakeAuthServer) Login(ctx context.Context, req *lauth.AuthReq) (*lauth.AuthRsp, error) {
+	if req.GetUsername() == "" || req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "username and password are required")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	ip, remoteAddr := s.clientIP.FromContext(ctx)
+	client := &auth.Client{
+		IP:         ip,
+		UserAgent:  first(md, userAgentKey),
+		RemoteAddr: remoteAddr,
+	}
+
+	authRsp, retryAfter, err := s.authHandler.Login(ctx, req, client)
+	if retryAfter > 0 {
+		seconds := int64((retryAfter + time.Second - 1) / time.Second)
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.FormatInt(seconds, 10)))
+	}
+	if err == auth.ErrLockedOut {
+		return nil, status.Error(codes.ResourceExhausted, "too many failed login attempts")
+	}
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	return authRsp, nil
+}
+
+func (s *LakeAuthServer) Logout(ctx context.Context, req *pb.LogoutReq) (*pb.LogoutRsp, error) {
+	log := tenant.GetLogger(ctx, "Logout")
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	token, _ := filter.ParseBearer(first(md, authorizationKey))
+	userID, _ := ctxutil.Read(ctx, filter.UserReferenceID)
+	if err := s.sessionStore.RevokeToken(ctx, userID, token); err != nil {
+		log.Errore(ctx, "revoke token found error", err)
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &pb.LogoutRsp{}, nil
+}
+
+func (s *LakeAuthServer) ListSessions(ctx context.Context, req *pb.ListSessionsReq) (*pb.ListSessionsRsp, error) {
+	log := tenant.GetLogger(ctx, "ListSessions")
+
+	userID, _ := ctxutil.Read(ctx, filter.UserReferenceID)
+	currentID, _ := ctxutil.Read(ctx, filter.SessionID)
+	sessions, err := s.sessionStore.List(ctx, userID)
+	if err != nil {
+		log.Errore(ctx, "list sessions found error", err)
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	rsp := &pb.ListSessionsRsp{Sessions: make([]*pb.Session, 0, len(sessions))}
+	for _, sess := range sessions {
+		rsp.Sessions = append(rsp.Sessions, &pb.Session{
+			Id:         sess.ID,
+			UserId:     sess.UserID,
+			CreatedAt:  timestamppb.New(sess.CreatedAt),
+			ExpiresAt:  timestamppb.New(sess.ExpiresAt),
+			UserAgent:  sess.UserAgent,
+			RemoteAddr: sess.RemoteAddr,
+			Current:    sess.ID == currentID,
+		})
+	}
+	return rsp, nil
+}
+
+func (s *LakeAuthServer) RevokeSession(ctx context.Context, req *pb.RevokeSessionReq) (*pb.RevokeSessionRsp, error) {
+	log := tenant.GetLogger(ctx, "RevokeSession")
+
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	userID, _ := ctxutil.Read(ctx, filter.UserReferenceID)
+	err := s.sessionStore.Revoke(ctx, userID, req.GetId())
+	if err == session.ErrSessionNotFound {
+		return nil, status.Error(codes.NotFound, "session not found")
+	}
+	if err != nil {
+		log.Errore(ctx, "revoke session found error", err, "sessionID", req.GetId())
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+	return &pb.RevokeSessionRsp{}, nil
+}
+
+func (s *LakeAuthServer) CreateApiKey(ctx context.Context, req *pb.CreateApiKeyReq) (*pb.CreateApiKeyRsp, error) {
+	reqBody := &auth.CreateAPIKeyReqBody{Name: req.GetName(), Scopes: req.GetScopes()}
+	if req.GetExpiresAt() != nil {
+		expiresAt := req.GetExpiresAt().AsTime()
+		reqBody.ExpiresAt = &expiresAt
+	}
+	rsp, err := s.authHandler.CreateKey(ctx, reqBody)
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	return &pb.CreateApiKeyRsp{ApiKey: toApiKey(rsp.APIKey), Key: rsp.Key}, nil
+}
+
+func (s *LakeAuthServer) ListApiKeys(ctx context.Context, req *pb.ListApiKeysReq) (*pb.ListApiKeysRsp, error) {
+	log := tenant.GetLogger(ctx, "ListApiKeys")
+
+	userID, _ := ctxutil.Read(ctx, filter.UserReferenceID)
+	keys, err := s.apiKeyStore.List(ctx, userID)
+	if err != nil {
+		log.Errore(ctx, "list api keys found error", err)
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	rsp := &pb.ListApiKeysRsp{ApiKeys: make([]*pb.ApiKey, 0, len(keys))}
+	for _, key := range keys {
+		rsp.ApiKeys = append(rsp.ApiKeys, toApiKey(key))
+	}
+	return rsp, nil
+}
+
+func (s *LakeAuthServer) RevokeApiKey(ctx context.Context, req *pb.RevokeApiKeyReq) (*pb.RevokeApiKeyRsp, error) {
+	log := tenant.GetLogger(ctx, "RevokeApiKey")
+
+	userID, _ := ctxutil.Read(ctx, filter.UserReferenceID)
+	err := s.apiKeyStore.Revoke(ctx, userID, req.GetId())
+	if err == apikey.ErrKeyNotFound {
+		return nil, status.Error(codes.NotFound, "api key not found")
+	}
+	if err != nil {
+		log.Errore(ctx, "revoke api key found error", err, "id", req.GetId())
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	log.Infow(ctx, "RevokeApiKey done", "id", req.GetId())
+	return &pb.RevokeApiKeyRsp{}, nil
+}
+
+// statusOf maps the problem returned by the shared auth handler to the status of the same meaning,
+// other errors are internal and never exposed
+func statusOf(err error) error {
+	var problem *responder.Problem
+	if !errors.As(err, &problem) {
+		return status.Error(codes.Internal, "internal error")
+	}
+	code := codes.Internal
+	switch problem.Status {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	case http.StatusGatewayTimeout:
+		code = codes.DeadlineExceeded
+	}
+	message := problem.Detail
+	for _, v := range problem.Violations {
+		message += ", " + v.Message
+	}
+	return status.Error(code, message)
+}
+
+func toApiKey(key *apikey.APIKey) *pb.ApiKey {
+	return &pb.ApiKey{
+		Id:         key.ID,
+		Prefix:     key.Prefix,
+		Name:       key.Name,
+		OwnerId:    key.OwnerID,
+		Scopes:     key.Scopes,
+		CreatedAt:  timestamppb.New(key.CreatedAt),
+		ExpiresAt:  optionalTimestamp(key.ExpiresAt),
+		LastUsedAt: optionalTimestamp(key.LastUsedAt),
+		RevokedAt:  optionalTimestamp(key.RevokedAt),
+	}
+}
+
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
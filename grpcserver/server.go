@@ -0,0 +1,113 @@
+package grpcserver
+
+import (
+	"context"
+	"github.com/google/wire"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"lake-go/audit"
+	"lake-go/filter"
+	"lake-go/policy"
+	pb "lake-go/proto/lake"
+	"lake-go/ratelimit"
+	"lake-go/tenant"
+	"net/http"
+	"strings"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvideGRPCServerConfig,
+		ProvideServer,
+		ProvideLakeAuthServer,
+		ProvideLakeAdminServer,
+	)
+)
+
+// Server is the grpc api of lake-go, it shares the listener of the http server
+type Server struct {
+	cnf          *GRPCServerConfig
+	grpcServer   *grpc.Server
+	healthServer *grpchealth.Server
+}
+
+func ProvideServer(ctx context.Context,
+	cnf *GRPCServerConfig,
+	authFilter *filter.AuthFilter,
+	policyEngine *policy.Engine,
+	tenantResolver *tenant.Resolver,
+	recorder *audit.Recorder,
+	rateLimiter *ratelimit.Limiter,
+	lakeAuthServer *LakeAuthServer,
+	lakeAdminServer *LakeAdminServer) (*Server, error) {
+	i := &interceptor{
+		authFilter:     authFilter,
+		policyEngine:   policyEngine,
+		tenantResolver: tenantResolver,
+		recorder:       recorder,
+		public: map[string]bool{
+			pb.LakeAuth_Login_FullMethodName:                                 true,
+			grpc_health_v1.Health_Check_FullMethodName:                       true,
+			grpc_health_v1.Health_Watch_FullMethodName:                       true,
+			"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo": true,
+			"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo":      true,
+		},
+	}
+
+	grpcServer := grpc.NewServer(
+		grpc.MaxRecvMsgSize(int(cnf.MaxRecvMsgBytes)),
+		// the limiter runs inside i.unary, it counts verified callers and rejected calls are logged with their stan
+		grpc.ChainUnaryInterceptor(i.unary, rateLimiter.UnaryInterceptor(pb.LakeAuth_Login_FullMethodName)),
+		grpc.ChainStreamInterceptor(i.stream),
+	)
+	pb.RegisterLakeAuthServer(grpcServer, lakeAuthServer)
+	pb.RegisterLakeAdminServer(grpcServer, lakeAdminServer)
+
+	healthServer := grpchealth.NewServer()
+	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
+	for service := range grpcServer.GetServiceInfo() {
+		healthServer.SetServingStatus(service, grpc_health_v1.HealthCheckResponse_SERVING)
+	}
+
+	if cnf.Reflection {
+		reflection.Register(grpcServer)
+	}
+
+	return &Server{
+		cnf:          cnf,
+		grpcServer:   grpcServer,
+		healthServer: healthServer,
+	}, nil
+}
+
+// Handler routes http/2 requests with a grpc content type to the grpc server and everything else
+// to next. h2c lets plaintext listeners accept http/2 without tls, behind tls it is negotiated by alpn.
+func (s *Server) Handler(next http.Handler) http.Handler {
+	if !s.cnf.Enable {
+		return next
+	}
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			s.grpcServer.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+	return h2c.NewHandler(http.HandlerFunc(fn), &http2.Server{})
+}
+
+// SetDraining reports every service as not serving, together with the http readiness probe
+func (s *Server) SetDraining() {
+	s.healthServer.Shutdown()
+}
+
+// Stop closes the grpc transports after http.Server.Shutdown returned. Shutdown waits for calls on
+// tls connections, h2c connections are hijacked and are only closed here. GracefulStop can't be
+// used, grpc doesn't implement draining for transports served through ServeHTTP.
+func (s *Server) Stop() {
+	s.grpcServer.Stop()
+}
@@ -0,0 +1,117 @@
+package admin
+
+import (
+	"lake-go/audit"
+	"lake-go/filter"
+	"lake-go/policy"
+	"lake-go/responder"
+	"lake-go/tenant"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const defaultAuditPageSize = 50
+
+type ListAuditEventsRsp struct {
+	Events []*audit.Event `json:"events"`
+	// NextCursor fetches the next, older page, it is empty on the last one
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// ListAuditEvents lists the audit trail of the tenant of the caller newest first, to callers
+// with the admin role only. The tenant is the one of the credential, never the tenant header.
+// GET /v1/audit?actor=alice&action=auth.login&resource=/v1/auth/*&outcome=denied&from=2024-01-01T00:00:00Z&limit=100&cursor=...
+// The cursor of the next page is returned in the body and in a Link header.
+func (h *AdminHandler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := tenant.GetLogger(ctx, "ListAuditEvents")
+
+	// the trail of other tenants is never visible, not even without a policy file
+	tenantID, ok := filter.TenantFromContext(ctx)
+	if requested, _ := tenant.FromContext(ctx); requested != tenantID {
+		ok = false
+	}
+	if !ok || !slices.Contains(filter.RolesFromContext(ctx), policy.AdminRole) {
+		log.Warnw(ctx, "audit trail access denied", "tenantResolved", ok)
+		responder.Error(w, r, responder.NewProblem(http.StatusForbidden, responder.ErrCodeForbidden, "the audit trail requires the admin role in a tenant"))
+		return
+	}
+
+	query := r.URL.Query()
+	q := &audit.Query{
+		Tenant:   tenantID,
+		Actor:    query.Get("actor"),
+		Action:   query.Get("action"),
+		Resource: query.Get("resource"),
+		Outcome:  query.Get("outcome"),
+		Limit:    defaultAuditPageSize,
+	}
+	var violations []*responder.Violation
+	var err error
+	if q.From, err = parseTime(query, "from"); err != nil {
+		violations = append(violations, &responder.Violation{Field: "from", Message: "from must be an RFC 3339 time"})
+	}
+	if q.To, err = parseTime(query, "to"); err != nil {
+		violations = append(violations, &responder.Violation{Field: "to", Message: "to must be an RFC 3339 time"})
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > int(h.auditConfig.MaxPageSize) {
+			violations = append(violations, &responder.Violation{Field: "limit",
+				Message: "limit must be between 1 and " + strconv.Itoa(int(h.auditConfig.MaxPageSize))})
+		}
+		q.Limit = limit
+	}
+	if raw := query.Get("cursor"); raw != "" {
+		if q.Before, err = audit.DecodeCursor(raw); err != nil {
+			violations = append(violations, &responder.Violation{Field: "cursor", Message: "cursor is invalid"})
+		}
+	}
+	if len(violations) > 0 {
+		responder.Error(w, r, responder.InvalidRequest("invalid audit query", violations...))
+		return
+	}
+
+	events, next, err := h.auditStore.List(ctx, q)
+	if err != nil {
+		log.Errore(ctx, "list audit events found error", err)
+		responder.Error(w, r, err)
+		return
+	}
+
+	rsp := &ListAuditEventsRsp{Events: events}
+	if next > 0 {
+		rsp.NextCursor = audit.EncodeCursor(next)
+		nextQuery := r.URL.Query()
+		nextQuery.Set("cursor", rsp.NextCursor)
+		w.Header().Set("Link", "<"+r.URL.Path+"?"+nextQuery.Encode()+`>; rel="next"`)
+	}
+	log.Infow(ctx, "ListAuditEvents done", "events", len(events))
+
+	if responder.Streaming(r) {
+		stream := responder.NewStream(w, r, http.StatusOK)
+		for _, e := range events {
+			if err := stream.Send(e); err != nil {
+				log.Warne(ctx, "stream audit events found error", err)
+				return
+			}
+		}
+		return
+	}
+	responder.Respond(w, r, http.StatusOK, rsp)
+}
+
+func parseTime(query url.Values, key string) (*time.Time, error) {
+	raw := query.Get(key)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
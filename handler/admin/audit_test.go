@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"context"
+	ctxutil "github.com/tyeryan/l-protocol/context"
+	"lake-go/filter"
+	"lake-go/policy"
+	"lake-go/tenant"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestListAuditEventsRefusesOtherTenants an admin only ever sees the trail of the tenant of its
+// credential, whatever tenant the request names. The refusals never reach the store.
+func TestListAuditEventsRefusesOtherTenants(t *testing.T) {
+	tests := []struct {
+		name            string
+		principalTenant string
+		requestTenant   string
+	}{
+		{name: "credential of another tenant", principalTenant: "acme", requestTenant: "globex"},
+		{name: "credential without tenant", principalTenant: "", requestTenant: "globex"},
+		{name: "no tenant at all", principalTenant: "", requestTenant: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.requestTenant != "" {
+				ctx = tenant.WithTenant(ctx, tt.requestTenant)
+			}
+			ctx = ctxutil.Add(ctx, filter.UserRoles, policy.AdminRole)
+			if tt.principalTenant != "" {
+				ctx = ctxutil.Add(ctx, filter.PrincipalTenant, tt.principalTenant)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/v1/audit", nil).WithContext(ctx)
+			w := httptest.NewRecorder()
+			(&AdminHandler{}).ListAuditEvents(w, r)
+
+			if w.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+// TestWithPrincipalRefusesOtherTenants the auth filter stops the request before any handler
+// when the tenant header names another tenant than the credential.
+func TestWithPrincipalRefusesOtherTenants(t *testing.T) {
+	ctx := tenant.WithTenant(context.Background(), "globex")
+	for _, principalTenant := range []string{"acme", ""} {
+		principal := &filter.Principal{UserID: "alice", Roles: []string{policy.AdminRole}, Tenant: principalTenant}
+		if _, err := filter.WithPrincipal(ctx, principal); err != tenant.ErrCrossTenant {
+			t.Fatalf("tenant %q: err = %v, want %v", principalTenant, err, tenant.ErrCrossTenant)
+		}
+	}
+}
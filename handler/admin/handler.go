@@ -0,0 +1,31 @@
+package admin
+
+import (
+	"context"
+	"github.com/google/wire"
+	"lake-go/audit"
+	"lake-go/policy"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvideAdminHandler,
+	)
+)
+
+type AdminHandler struct {
+	policyEngine *policy.Engine
+	auditStore   *audit.Store
+	auditConfig  *audit.AuditConfig
+}
+
+func ProvideAdminHandler(ctx context.Context,
+	policyEngine *policy.Engine,
+	auditStore *audit.Store,
+	auditConfig *audit.AuditConfig) (*AdminHandler, error) {
+	return &AdminHandler{
+		policyEngine: policyEngine,
+		auditStore:   auditStore,
+		auditConfig:  auditConfig,
+	}, nil
+}
@@ -0,0 +1,52 @@
+package admin
+
+import (
+	"lake-go/policy"
+	"lake-go/responder"
+	"lake-go/tenant"
+	"net/http"
+	"strings"
+)
+
+type PolicyCheckRsp struct {
+	Roles  []string `json:"roles"`
+	Method string   `json:"method"`
+	Route  string   `json:"route"`
+	*policy.Decision
+}
+
+// PolicyCheck answers whether a caller with the given roles may call a route, for debugging the policy.
+// GET /v1/admin/policy/check?roles=analyst,viewer&method=GET&route=/v1/auth/sessions
+func (h *AdminHandler) PolicyCheck(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := tenant.GetLogger(ctx, "PolicyCheck")
+
+	query := r.URL.Query()
+	method := strings.ToUpper(query.Get("method"))
+	route := query.Get("route")
+	var violations []*responder.Violation
+	if method == "" {
+		violations = append(violations, &responder.Violation{Field: "method", Message: "method is required"})
+	}
+	if route == "" {
+		violations = append(violations, &responder.Violation{Field: "route", Message: "route is required"})
+	}
+	if len(violations) > 0 {
+		responder.Error(w, r, responder.InvalidRequest("method and route are required", violations...))
+		return
+	}
+	var roles []string
+	if query.Get("roles") != "" {
+		roles = strings.Split(query.Get("roles"), ",")
+	}
+
+	decision := h.policyEngine.Evaluate(method, route, roles)
+	log.Infow(ctx, "PolicyCheck done", "roles", roles, "method", method, "route", route, "allowed", decision.Allowed)
+
+	responder.Respond(w, r, http.StatusOK, &PolicyCheckRsp{
+		Roles:    roles,
+		Method:   method,
+		Route:    route,
+		Decision: decision,
+	})
+}
@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"fmt"
+	"io"
+	"lake-go/logging"
+	"lake-go/responder"
+	"lake-go/tenant"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"time"
+)
+
+type BuildInfoRsp struct {
+	GoVersion string            `json:"goVersion"`
+	Path      string            `json:"path"`
+	Version   string            `json:"version"`
+	Settings  map[string]string `json:"settings"`
+	Deps      map[string]string `json:"deps"`
+}
+
+type LogLevelsRsp struct {
+	Levels []*logging.Level `json:"levels"`
+}
+
+// DumpGoroutines writes the stacks of all goroutines, without the size limit of runtime.Stack
+func DumpGoroutines(w io.Writer) error {
+	return pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// Goroutines downloads the stacks of all goroutines as a text file.
+// GET /admin/goroutines
+func (h *AdminHandler) Goroutines(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := tenant.GetLogger(ctx, "Goroutines")
+
+	name := fmt.Sprintf("goroutines-%s.txt", time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	if err := DumpGoroutines(w); err != nil {
+		log.Errore(ctx, "dump goroutines found error", err)
+		return
+	}
+	log.Infow(ctx, "Goroutines done", "goroutines", runtime.NumGoroutine())
+}
+
+// BuildInfo the go version, the vcs revision and the module versions of the binary.
+// GET /admin/buildinfo
+func (h *AdminHandler) BuildInfo(w http.ResponseWriter, r *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		responder.Error(w, r, responder.NewProblem(http.StatusNotFound, responder.ErrCodeNotFound, "binary has no build info"))
+		return
+	}
+
+	rsp := &BuildInfoRsp{
+		GoVersion: info.GoVersion,
+		Path:      info.Main.Path,
+		Version:   info.Main.Version,
+		Settings:  map[string]string{},
+		Deps:      map[string]string{},
+	}
+	for _, s := range info.Settings {
+		rsp.Settings[s.Key] = s.Value
+	}
+	for _, dep := range info.Deps {
+		rsp.Deps[dep.Path] = dep.Version
+	}
+	responder.Respond(w, r, http.StatusOK, rsp)
+}
+
+// LogLevels lists the global log level and the per logger overrides.
+// GET /admin/loglevel
+func (h *AdminHandler) LogLevels(w http.ResponseWriter, r *http.Request) {
+	responder.Respond(w, r, http.StatusOK, &LogLevelsRsp{Levels: logging.Levels()})
+}
+
+// SetLogLevel changes the level of one logger name, a trailing * matches a prefix, or the global
+// level when logger is empty. An empty level removes the override of the logger.
+// PUT /admin/loglevel {"logger": "loginguard.*", "level": "debug"}
+func (h *AdminHandler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := tenant.GetLogger(ctx, "SetLogLevel")
+
+	var reqBody logging.Level
+	if err := responder.Decode(w, r, &reqBody); err != nil {
+		responder.Error(w, r, err)
+		return
+	}
+	if err := logging.SetLevel(reqBody.Logger, reqBody.Level); err != nil {
+		responder.Error(w, r, responder.InvalidRequest("invalid log level",
+			&responder.Violation{Field: "level", Message: "level must be one of debug, info, warn, error, dpanic, panic, fatal"}))
+		return
+	}
+
+	log.Infow(ctx, "SetLogLevel done", "targetLogger", reqBody.Logger, "level", reqBody.Level)
+	responder.Respond(w, r, http.StatusOK, &LogLevelsRsp{Levels: logging.Levels()})
+}
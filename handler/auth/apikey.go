@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	ctxutil "github.com/tyeryan/l-protocol/context"
+	"lake-go/apikey"
+	"lake-go/filter"
+	"lake-go/responder"
+	"lake-go/tenant"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const ErrCodeAPIKeyNotFound = "API_KEY_NOT_FOUND"
+
+type CreateAPIKeyReqBody struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type CreateAPIKeyRsp struct {
+	APIKey *apikey.APIKey `json:"apiKey"`
+	// Key is the plaintext key, it is only returned once
+	Key string `json:"key"`
+}
+
+type ListAPIKeysRsp struct {
+	APIKeys []*apikey.APIKey `json:"apiKeys"`
+}
+
+// CreateAPIKey creates a key for the current user, see CreateKey
+func (h *AuthHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var reqBody CreateAPIKeyReqBody
+	if err := responder.Decode(w, r, &reqBody); err != nil {
+		responder.Error(w, r, err)
+		return
+	}
+	rsp, err := h.CreateKey(r.Context(), &reqBody)
+	if err != nil {
+		responder.Error(w, r, err)
+		return
+	}
+	responder.Respond(w, r, http.StatusCreated, rsp)
+}
+
+// CreateKey creates a key for the current user in the current tenant, the scopes must be a subset
+// of the user roles. Rejected requests are returned as problems, the http and the grpc api share it.
+func (h *AuthHandler) CreateKey(ctx context.Context, reqBody *CreateAPIKeyReqBody) (*CreateAPIKeyRsp, error) {
+	log := tenant.GetLogger(ctx, "CreateAPIKey")
+
+	if _, ok := ctxutil.Read(ctx, filter.APIKeyID); ok {
+		return nil, responder.NewProblem(http.StatusForbidden, responder.ErrCodeForbidden, "api keys can't create api keys")
+	}
+	if reqBody.Name == "" {
+		return nil, responder.InvalidRequest("invalid api key",
+			&responder.Violation{Field: "name", Message: "name is required"})
+	}
+	if reqBody.ExpiresAt != nil && !reqBody.ExpiresAt.After(time.Now()) {
+		return nil, responder.InvalidRequest("invalid api key",
+			&responder.Violation{Field: "expiresAt", Message: "expiresAt must be in the future"})
+	}
+	roles := filter.RolesFromContext(ctx)
+	for _, scope := range reqBody.Scopes {
+		if !slices.Contains(roles, scope) {
+			return nil, responder.NewProblem(http.StatusForbidden, responder.ErrCodeForbidden, "scope "+scope+" is not granted to the current user")
+		}
+	}
+
+	userID, _ := ctxutil.Read(ctx, filter.UserReferenceID)
+	tenantID, _ := tenant.FromContext(ctx)
+	key, plaintext, err := h.apiKeyStore.Create(ctx, userID, tenantID, reqBody.Name, reqBody.Scopes, reqBody.ExpiresAt)
+	if err != nil {
+		log.Errore(ctx, "create api key found error", err)
+		return nil, err
+	}
+
+	log.Infow(ctx, "CreateAPIKey done", "prefix", key.Prefix, "scopes", key.Scopes)
+	return &CreateAPIKeyRsp{APIKey: key, Key: plaintext}, nil
+}
+
+// ListAPIKeys lists the keys of the current user, secrets are never returned
+func (h *AuthHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := tenant.GetLogger(ctx, "ListAPIKeys")
+
+	userID, _ := ctxutil.Read(ctx, filter.UserReferenceID)
+	keys, err := h.apiKeyStore.List(ctx, userID)
+	if err != nil {
+		log.Errore(ctx, "list api keys found error", err)
+		responder.Error(w, r, err)
+		return
+	}
+
+	if responder.Streaming(r) {
+		stream := responder.NewStream(w, r, http.StatusOK)
+		for _, key := range keys {
+			if err := stream.Send(key); err != nil {
+				log.Warne(ctx, "stream api keys found error", err)
+				return
+			}
+		}
+		return
+	}
+	responder.Respond(w, r, http.StatusOK, &ListAPIKeysRsp{APIKeys: keys})
+}
+
+// RevokeAPIKey revokes a key of the current user
+func (h *AuthHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := tenant.GetLogger(ctx, "RevokeAPIKey")
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		responder.Error(w, r, responder.InvalidRequest("invalid api key id",
+			&responder.Violation{Field: "id", Message: "id must be a number"}))
+		return
+	}
+
+	userID, _ := ctxutil.Read(ctx, filter.UserReferenceID)
+	err = h.apiKeyStore.Revoke(ctx, userID, id)
+	if err == apikey.ErrKeyNotFound {
+		responder.Error(w, r, responder.NewProblem(http.StatusNotFound, ErrCodeAPIKeyNotFound, "api key not found"))
+		return
+	}
+	if err != nil {
+		log.Errore(ctx, "revoke api key found error", err, "id", id)
+		responder.Error(w, r, err)
+		return
+	}
+
+	log.Infow(ctx, "RevokeAPIKey done", "id", id)
+	responder.NoContent(w, r)
+}
@@ -1,50 +1,137 @@
 package auth
 
 import (
-	"encoding/json"
-	"github.com/go-chi/render"
+	"context"
+	"errors"
 	"github.com/tyeryan/l-protocol/go/lauth"
-	logutil "github.com/tyeryan/l-protocol/log"
-	"io"
+	"lake-go/audit"
+	"lake-go/responder"
+	"lake-go/tenant"
 	"net/http"
+	"strconv"
+	"time"
 )
 
+// ErrLockedOut is returned by Login while the username or the client ip is locked out
+var ErrLockedOut = errors.New("locked out after too many failed logins")
+
+// Client describes where a login comes from, for the login guard and the session index
+type Client struct {
+	IP         string
+	UserAgent  string
+	RemoteAddr string
+}
+
 func (h *AuthHandler) Authenticate(w http.ResponseWriter, r *http.Request) {
-	log := logutil.GetLogger("Authenticate")
-	var err error
 	ctx := r.Context()
+	log := tenant.GetLogger(ctx, "Authenticate")
 
 	defer func() {
 		log.Infow(ctx, "Authenticate done")
 	}()
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	// the request is decoded straight into the l-auth message, so protobuf clients can send it as is
+	authReq := &lauth.AuthReq{}
+	if err := responder.Decode(w, r, authReq); err != nil {
+		responder.Error(w, r, err)
+		return
+	}
+	if violations := validateAuthReq(authReq); len(violations) > 0 {
+		responder.Error(w, r, responder.InvalidRequest("username and password are required", violations...))
+		return
+	}
+
+	client := &Client{IP: h.clientIP.FromRequest(r), UserAgent: r.UserAgent(), RemoteAddr: r.RemoteAddr}
+	authRsp, retryAfter, err := h.Login(ctx, authReq, client)
+	if retryAfter > 0 {
+		setRetryAfter(w, retryAfter)
+	}
+	if err == ErrLockedOut {
+		responder.Error(w, r, responder.NewProblem(http.StatusTooManyRequests, ErrCodeTooManyAttempts, "too many failed login attempts"))
 		return
 	}
-	var reqBody AuthenticationReqBody
-	if err := json.Unmarshal(body, &reqBody); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err != nil {
+		responder.Error(w, r, err)
 		return
 	}
 
-	// call auth client here
-	authReq := &lauth.AuthReq{
-		Username: reqBody.Username,
-		Password: reqBody.Password,
+	responder.Respond(w, r, http.StatusOK, authRsp)
+}
+
+// Login authenticates with l-auth behind the login guard and records the session of the new token.
+// The returned duration is how long the caller is locked out, ErrLockedOut is returned when it already
+// was, any other error is the problem the error of l-auth maps to. The http and the grpc api share it.
+func (h *AuthHandler) Login(ctx context.Context, authReq *lauth.AuthReq, client *Client) (*lauth.AuthRsp, time.Duration, error) {
+	log := tenant.GetLogger(ctx, "Login")
+
+	if retryAfter := h.loginGuard.Check(ctx, authReq.GetUsername(), client.IP); retryAfter > 0 {
+		log.Warnw(ctx, "login attempt while locked out", "ip", client.IP, "retryAfter", retryAfter)
+		h.recordLogin(ctx, authReq, client, audit.OutcomeDenied, "locked out")
+		return nil, retryAfter, ErrLockedOut
 	}
 
 	authRsp, err := h.client.Authenticate(ctx, authReq)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Errore(ctx, "authenticate with l-auth found error", err)
+		var lockout time.Duration
+		problem := grpcErrorToProblem(err)
+		if problem.Status == http.StatusUnauthorized {
+			lockout = h.loginGuard.Failed(ctx, authReq.GetUsername(), client.IP)
+		}
+		// the message of l-auth may carry internals, the trail keeps the problem code it maps to
+		h.recordLogin(ctx, authReq, client, audit.OutcomeOf(problem.Status), problem.Code)
+		return nil, lockout, problem
 	}
 
-	render.Status(r, http.StatusOK)
-	render.JSON(w, r, authRsp)
+	h.loginGuard.Succeeded(ctx, authReq.GetUsername())
+	h.recordLogin(ctx, authReq, client, audit.OutcomeSuccess, "")
+	h.createSession(ctx, authRsp.GetToken(), client)
+	return authRsp, 0, nil
 }
 
-type AuthenticationReqBody struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+// createSession records the new token in the session index of its user, a failure here
+// doesn't fail the login as the token is valid either way.
+func (h *AuthHandler) createSession(ctx context.Context, token string, client *Client) {
+	log := tenant.GetLogger(ctx, "Login")
+
+	principal, err := h.authFilter.Verify(ctx, token)
+	if err != nil {
+		log.Warne(ctx, "resolve user of new token found error", err)
+		return
+	}
+	if _, err := h.sessionStore.Create(ctx, principal.UserID, token, client.UserAgent, client.RemoteAddr); err != nil {
+		log.Warne(ctx, "create session found error", err)
+	}
+}
+
+// recordLogin audits a login attempt, the actor is the username as nobody is authenticated yet
+func (h *AuthHandler) recordLogin(ctx context.Context, authReq *lauth.AuthReq, client *Client, outcome string, reason string) {
+	detail := map[string]string{"ip": client.IP, "userAgent": client.UserAgent}
+	if reason != "" {
+		detail["reason"] = reason
+	}
+	h.recorder.Record(ctx, &audit.Event{
+		Actor:    authReq.GetUsername(),
+		Action:   audit.ActionLogin,
+		Resource: "user/" + authReq.GetUsername(),
+		Outcome:  outcome,
+		Detail:   detail,
+	})
+}
+
+// setRetryAfter sets the Retry-After header in whole seconds, rounded up
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	seconds := int64((d + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
+
+func validateAuthReq(req *lauth.AuthReq) []*responder.Violation {
+	var violations []*responder.Violation
+	if req.GetUsername() == "" {
+		violations = append(violations, &responder.Violation{Field: "username", Message: "username is required"})
+	}
+	if req.GetPassword() == "" {
+		violations = append(violations, &responder.Violation{Field: "password", Message: "password is required"})
+	}
+	return violations
 }
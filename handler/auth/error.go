@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"lake-go/responder"
+	"net/http"
+)
+
+const (
+	ErrCodeInvalidCredentials = "INVALID_CREDENTIALS"
+	ErrCodeAuthUnavailable    = "AUTH_UNAVAILABLE"
+	ErrCodeAuthTimeout        = "AUTH_TIMEOUT"
+	ErrCodeTooManyAttempts    = "TOO_MANY_ATTEMPTS"
+)
+
+// grpcErrorToProblem maps an error returned by l-auth to the problem we show to the client,
+// the raw grpc error message is never exposed.
+func grpcErrorToProblem(err error) *responder.Problem {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		return responder.NewProblem(http.StatusUnauthorized, ErrCodeInvalidCredentials, "invalid username or password")
+	case codes.InvalidArgument:
+		return responder.InvalidRequest("invalid authentication request")
+	case codes.Unavailable:
+		return responder.NewProblem(http.StatusServiceUnavailable, ErrCodeAuthUnavailable, "authentication service is unavailable")
+	case codes.DeadlineExceeded:
+		return responder.NewProblem(http.StatusGatewayTimeout, ErrCodeAuthTimeout, "authentication service timed out")
+	default:
+		return responder.NewProblem(http.StatusInternalServerError, responder.ErrCodeInternal, "internal error")
+	}
+}
@@ -4,6 +4,12 @@ import (
 	"context"
 	"github.com/google/wire"
 	pb "github.com/tyeryan/l-protocol/go/lauth"
+	"lake-go/apikey"
+	"lake-go/audit"
+	"lake-go/clientip"
+	"lake-go/filter"
+	"lake-go/loginguard"
+	"lake-go/session"
 )
 
 var (
@@ -13,11 +19,30 @@ var (
 )
 
 type AuthHandler struct {
-	client pb.LAuthClient
+	client       pb.LAuthClient
+	authFilter   *filter.AuthFilter
+	sessionStore *session.Store
+	apiKeyStore  *apikey.Store
+	loginGuard   *loginguard.Guard
+	clientIP     *clientip.Resolver
+	recorder     *audit.Recorder
 }
 
-func ProvideAuthHandler(ctx context.Context, client pb.LAuthClient) (*AuthHandler, error) {
+func ProvideAuthHandler(ctx context.Context,
+	client pb.LAuthClient,
+	authFilter *filter.AuthFilter,
+	sessionStore *session.Store,
+	apiKeyStore *apikey.Store,
+	loginGuard *loginguard.Guard,
+	clientIP *clientip.Resolver,
+	recorder *audit.Recorder) (*AuthHandler, error) {
 	return &AuthHandler{
-		client: client,
+		client:       client,
+		authFilter:   authFilter,
+		sessionStore: sessionStore,
+		apiKeyStore:  apiKeyStore,
+		loginGuard:   loginGuard,
+		clientIP:     clientIP,
+		recorder:     recorder,
 	}, nil
 }
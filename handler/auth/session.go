@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"github.com/go-chi/chi/v5"
+	ctxutil "github.com/tyeryan/l-protocol/context"
+	"lake-go/filter"
+	"lake-go/responder"
+	"lake-go/session"
+	"lake-go/tenant"
+	"net/http"
+)
+
+const ErrCodeSessionNotFound = "SESSION_NOT_FOUND"
+
+type SessionRsp struct {
+	*session.Session
+	Current bool `json:"current"`
+}
+
+type ListSessionsRsp struct {
+	Sessions []*SessionRsp `json:"sessions"`
+}
+
+// Logout revokes the bearer token of the request
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := tenant.GetLogger(ctx, "Logout")
+
+	token, _ := filter.BearerToken(r)
+	userID, _ := ctxutil.Read(ctx, filter.UserReferenceID)
+	if err := h.sessionStore.RevokeToken(ctx, userID, token); err != nil {
+		log.Errore(ctx, "revoke token found error", err)
+		responder.Error(w, r, err)
+		return
+	}
+
+	log.Infow(ctx, "Logout done")
+	responder.NoContent(w, r)
+}
+
+// ListSessions lists the active sessions of the current user
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := tenant.GetLogger(ctx, "ListSessions")
+
+	userID, _ := ctxutil.Read(ctx, filter.UserReferenceID)
+	currentID, _ := ctxutil.Read(ctx, filter.SessionID)
+	sessions, err := h.sessionStore.List(ctx, userID)
+	if err != nil {
+		log.Errore(ctx, "list sessions found error", err)
+		responder.Error(w, r, err)
+		return
+	}
+
+	rsp := &ListSessionsRsp{Sessions: make([]*SessionRsp, 0, len(sessions))}
+	for _, s := range sessions {
+		rsp.Sessions = append(rsp.Sessions, &SessionRsp{Session: s, Current: s.ID == currentID})
+	}
+
+	if responder.Streaming(r) {
+		stream := responder.NewStream(w, r, http.StatusOK)
+		for _, s := range rsp.Sessions {
+			if err := stream.Send(s); err != nil {
+				log.Warne(ctx, "stream sessions found error", err)
+				return
+			}
+		}
+		return
+	}
+	responder.Respond(w, r, http.StatusOK, rsp)
+}
+
+// DeleteSession revokes one session of the current user
+func (h *AuthHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := tenant.GetLogger(ctx, "DeleteSession")
+
+	userID, _ := ctxutil.Read(ctx, filter.UserReferenceID)
+	sessionID := chi.URLParam(r, "id")
+	err := h.sessionStore.Revoke(ctx, userID, sessionID)
+	if err == session.ErrSessionNotFound {
+		responder.Error(w, r, responder.NewProblem(http.StatusNotFound, ErrCodeSessionNotFound, "session not found"))
+		return
+	}
+	if err != nil {
+		log.Errore(ctx, "revoke session found error", err, "sessionID", sessionID)
+		responder.Error(w, r, err)
+		return
+	}
+
+	log.Infow(ctx, "DeleteSession done", "sessionID", sessionID)
+	responder.NoContent(w, r)
+}
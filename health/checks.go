@@ -0,0 +1,49 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/go-redis/redis"
+	"github.com/tyeryan/l-common-util/cache"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"lake-go/db"
+)
+
+// RedisCheck pings every master of the redis cluster, the client is looked up on every run
+// as the cache replaces it after timeouts
+func RedisCheck(cacheClient cache.DistributedCache) CheckFunc {
+	return func(ctx context.Context) error {
+		return cacheClient.GetClient().WithContext(ctx).ForEachMaster(func(master *redis.Client) error {
+			return master.WithContext(ctx).Ping().Err()
+		})
+	}
+}
+
+// GRPCConnCheck waits for the connection to become ready, idle connections are asked to connect
+func GRPCConnCheck(conn *grpc.ClientConn) CheckFunc {
+	return func(ctx context.Context) error {
+		state := conn.GetState()
+		if state == connectivity.Idle {
+			conn.Connect()
+		}
+		for state != connectivity.Ready {
+			if state == connectivity.Shutdown {
+				return fmt.Errorf("connection is %s", state)
+			}
+			if !conn.WaitForStateChange(ctx, state) {
+				return fmt.Errorf("connection is %s: %w", state, ctx.Err())
+			}
+			state = conn.GetState()
+		}
+		return nil
+	}
+}
+
+// DatabaseCheck runs a query through the pool
+func DatabaseCheck(conn *sql.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		return db.HealthCheck(ctx, conn)
+	}
+}
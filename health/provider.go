@@ -0,0 +1,52 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/wire"
+	"github.com/tyeryan/l-common-util/cache"
+	"github.com/tyeryan/l-common-util/config"
+	"lake-go/grpcclient"
+	"time"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvideHealthConfig,
+		ProvideRegistry,
+	)
+)
+
+type HealthConfig struct {
+	CacheTTLInMs        int32 `configdefault:"5000" configstruct:"HEALTH_CONFIG_CACHE_TTL_IN_MS" configvalidate:"duration=0s.."`
+	RedisTimeoutInMs    int32 `configdefault:"1000" configstruct:"HEALTH_CONFIG_REDIS_TIMEOUT_IN_MS" configvalidate:"duration=1ms.."`
+	LAuthTimeoutInMs    int32 `configdefault:"2000" configstruct:"HEALTH_CONFIG_L_AUTH_TIMEOUT_IN_MS" configvalidate:"duration=1ms.."`
+	DatabaseTimeoutInMs int32 `configdefault:"1000" configstruct:"HEALTH_CONFIG_DATABASE_TIMEOUT_IN_MS" configvalidate:"duration=1ms.."`
+	// DrainDelayInSec how long readiness fails before the http server shuts down on SIGTERM
+	DrainDelayInSec int32 `configdefault:"5" configstruct:"HEALTH_CONFIG_DRAIN_DELAY_IN_SEC" configvalidate:"duration=0s.."`
+}
+
+func ProvideHealthConfig(ctx context.Context, configStore config.ConfigStore) (*HealthConfig, error) {
+	cnf := HealthConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
+}
+
+// ProvideRegistry registers the checks of the dependencies lake-go can't serve without
+func ProvideRegistry(ctx context.Context,
+	cnf *HealthConfig,
+	cacheClient cache.DistributedCache,
+	lAuthConn *grpcclient.LAuthConn,
+	db *sql.DB) *Registry {
+	registry := NewRegistry(millis(cnf.CacheTTLInMs))
+	registry.Register("redis", millis(cnf.RedisTimeoutInMs), RedisCheck(cacheClient))
+	registry.Register("l-auth", millis(cnf.LAuthTimeoutInMs), GRPCConnCheck(lAuthConn.ClientConn))
+	registry.Register("database", millis(cnf.DatabaseTimeoutInMs), DatabaseCheck(db))
+	return registry
+}
+
+func millis(ms int32) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
@@ -0,0 +1,140 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc returns nil when the component is healthy
+type CheckFunc func(ctx context.Context) error
+
+// ComponentReport is the result of one check
+type ComponentReport struct {
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	CheckedAt  time.Time `json:"checkedAt"`
+}
+
+// Report is the readiness of the service and of every registered component
+type Report struct {
+	Status     string                      `json:"status"`
+	Draining   bool                        `json:"draining,omitempty"`
+	Components map[string]*ComponentReport `json:"components"`
+}
+
+type check struct {
+	name    string
+	timeout time.Duration
+	fn      CheckFunc
+
+	mutex  sync.Mutex
+	result *ComponentReport
+}
+
+// Registry runs the registered checks with their own timeout and caches each result for the
+// cache ttl, so frequent probes don't hammer the dependencies.
+type Registry struct {
+	cacheTTL time.Duration
+	draining atomic.Bool
+
+	mutex  sync.RWMutex
+	checks []*check
+}
+
+func NewRegistry(cacheTTL time.Duration) *Registry {
+	return &Registry{
+		cacheTTL: cacheTTL,
+	}
+}
+
+// Register adds a check, timeout bounds every run of it
+func (r *Registry) Register(name string, timeout time.Duration, fn CheckFunc) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.checks = append(r.checks, &check{name: name, timeout: timeout, fn: fn})
+}
+
+// SetDraining makes readiness fail from now on, called when the service is about to shut down
+func (r *Registry) SetDraining() {
+	r.draining.Store(true)
+}
+
+// Readiness runs all checks in parallel, the service is ready when none of them fails and it is not draining
+func (r *Registry) Readiness(ctx context.Context) *Report {
+	r.mutex.RLock()
+	checks := r.checks
+	r.mutex.RUnlock()
+
+	report := &Report{
+		Status:     StatusOK,
+		Draining:   r.draining.Load(),
+		Components: make(map[string]*ComponentReport, len(checks)),
+	}
+
+	results := make([]*ComponentReport, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, r.cacheTTL)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		report.Components[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	if report.Draining {
+		report.Status = StatusFail
+	}
+	return report
+}
+
+func (c *check) run(ctx context.Context, cacheTTL time.Duration) *ComponentReport {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.result != nil && time.Since(c.result.CheckedAt) < cacheTTL {
+		return c.result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	result := &ComponentReport{Status: StatusOK, CheckedAt: start}
+	if err := c.call(ctx); err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	c.result = result
+	return result
+}
+
+// call enforces the timeout even for clients which don't honour the context
+func (c *check) call(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- c.fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("check timed out after %s", c.timeout)
+	}
+}
@@ -8,19 +8,57 @@ import (
 	"github.com/google/wire"
 	"github.com/tyeryan/l-common-util/apm"
 	"github.com/tyeryan/l-common-util/cache"
-	"github.com/tyeryan/l-common-util/config"
+	"lake-go/audit"
+	"lake-go/config"
+	"lake-go/db"
 	"lake-go/filter"
+	"lake-go/grpcserver"
+	"lake-go/migrate"
 	"lake-go/router"
-	"net/http"
 )
 
-func injectRoutes(ctx context.Context) (http.Handler, error) {
+func injectService(ctx context.Context) (*service, error) {
 	panic(wire.Build(
 		config.WireSet,
 		apm.WireSet,
-		cache.WireSet,
+		cache.ProvideRedisConfig,
+		cache.ProvideRedisClient,
 		filter.ProvideAccessLogFilter,
+		filter.ProvideAuthFilterConfig,
 		filter.ProvideAuthFilter,
+		filter.ProvidePolicyFilter,
+		filter.ProvideRequestLoggerConfig,
+		filter.ProvideRequestLogger,
+		filter.ProvideCORSConfig,
+		filter.ProvideCORS,
 		router.WireSet,
+		grpcserver.WireSet,
+		migrate.WireSet,
+		provideService,
+	))
+}
+
+// injectAuditStore builds only what the audit commands need, no server is started
+func injectAuditStore(ctx context.Context) (*audit.Store, error) {
+	panic(wire.Build(
+		config.WireSet,
+		db.WireSet,
+		audit.ProvideStore,
+	))
+}
+
+// injectMigrator builds only what the migrate commands need, no server is started
+func injectMigrator(ctx context.Context) (*migrate.Migrator, error) {
+	panic(wire.Build(
+		config.WireSet,
+		db.WireSet,
+		migrate.WireSet,
+	))
+}
+
+// injectConfigStore builds only the config store, to check and print the configs
+func injectConfigStore(ctx context.Context) (*config.Store, error) {
+	panic(wire.Build(
+		config.WireSet,
 	))
 }
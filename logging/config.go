@@ -0,0 +1,19 @@
+package logging
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// LogConfig is the global level, logutil reads it from the environment before any config is loaded
+type LogConfig struct {
+	Level string `configdefault:"info" configstruct:"LOG_LEVEL"`
+}
+
+// Validate checks what the types don't, a reload with an invalid config is rejected
+func (cnf *LogConfig) Validate() error {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(cnf.Level)); err != nil {
+		return ErrInvalidLevel
+	}
+	return nil
+}
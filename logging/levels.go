@@ -0,0 +1,149 @@
+package logging
+
+import (
+	"errors"
+	logutil "github.com/tyeryan/l-protocol/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// loggerKey is the field logutil.GetLogger names its loggers with
+const loggerKey = "logger"
+
+var ErrInvalidLevel = errors.New("invalid log level")
+
+var (
+	// global is the AtomicLevel of logutil once Install ran, the one logutil.EnableDebug sets
+	global = zap.NewAtomicLevelAt(zap.InfoLevel)
+
+	mu sync.Mutex
+	// overrides holds an immutable map[string]zapcore.Level, replaced on every change so
+	// the log calls read it without locking
+	overrides atomic.Value
+)
+
+// Level is the level of one logger name, a name ending in * matches every logger with that prefix
+type Level struct {
+	Logger string `json:"logger"`
+	Level  string `json:"level"`
+}
+
+// Install wraps the core of logutil so levels can be changed at runtime per logger name. The global
+// level stays the AtomicLevel logutil reads LOG_LEVEL into, SetLevel and logutil.EnableDebug change
+// the same level. Loggers created before Install, like the package loggers of l-common-util, follow
+// the global level but not the overrides.
+func Install() {
+	overrides.Store(map[string]zapcore.Level{})
+	global = atomicLevelOf(logutil.LoggerConfig)
+	logutil.LoggerConfig = &levelCore{Core: logutil.LoggerConfig}
+}
+
+// atomicLevelOf finds the AtomicLevel the core of logutil was built with, logutil doesn't export it.
+// A core without one gets a new AtomicLevel at its current level.
+func atomicLevelOf(core zapcore.Core) zap.AtomicLevel {
+	if v := reflect.ValueOf(core); v.Kind() == reflect.Ptr && v.Elem().Kind() == reflect.Struct {
+		if f := v.Elem().FieldByName("LevelEnabler"); f.IsValid() && f.CanInterface() {
+			if level, ok := f.Interface().(zap.AtomicLevel); ok {
+				return level
+			}
+		}
+	}
+	return zap.NewAtomicLevelAt(zapcore.LevelOf(core))
+}
+
+// SetLevel sets the level of a logger name, or the global level when logger is empty.
+// An empty level removes the override of the logger.
+func SetLevel(logger string, level string) error {
+	if logger == "" {
+		var lvl zapcore.Level
+		if err := lvl.UnmarshalText([]byte(level)); err != nil || level == "" {
+			return ErrInvalidLevel
+		}
+		global.SetLevel(lvl)
+		return nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	current := overrides.Load().(map[string]zapcore.Level)
+	next := make(map[string]zapcore.Level, len(current)+1)
+	for name, lvl := range current {
+		next[name] = lvl
+	}
+	if level == "" {
+		delete(next, logger)
+	} else {
+		var lvl zapcore.Level
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return ErrInvalidLevel
+		}
+		next[logger] = lvl
+	}
+	overrides.Store(next)
+	return nil
+}
+
+// Levels lists the global level, with an empty logger name, followed by the overrides
+func Levels() []*Level {
+	current, _ := overrides.Load().(map[string]zapcore.Level)
+	levels := make([]*Level, 0, len(current)+1)
+	for name, lvl := range current {
+		levels = append(levels, &Level{Logger: name, Level: lvl.String()})
+	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Logger < levels[j].Logger })
+	return append([]*Level{{Level: global.Level().String()}}, levels...)
+}
+
+// levelOf the override of the exact name wins over the longest matching prefix, then the global level
+func levelOf(logger string) zapcore.Level {
+	current, _ := overrides.Load().(map[string]zapcore.Level)
+	if len(current) == 0 {
+		return global.Level()
+	}
+	if lvl, ok := current[logger]; ok {
+		return lvl
+	}
+	matched := -1
+	lvl := global.Level()
+	for name, l := range current {
+		prefix := strings.TrimSuffix(name, "*")
+		if prefix != name && strings.HasPrefix(logger, prefix) && len(prefix) > matched {
+			matched = len(prefix)
+			lvl = l
+		}
+	}
+	return lvl
+}
+
+// levelCore filters entries by the level of the logger name it picked up from the logger field. Entries
+// it lets through are written by the core of logutil, which doesn't check the level again on Write.
+type levelCore struct {
+	zapcore.Core
+	logger string
+}
+
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return level >= levelOf(c.logger)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	logger := c.logger
+	for _, f := range fields {
+		if f.Key == loggerKey && f.Type == zapcore.StringType {
+			logger = f.String
+		}
+	}
+	return &levelCore{Core: c.Core.With(fields), logger: logger}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
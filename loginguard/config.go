@@ -0,0 +1,26 @@
+package loginguard
+
+import (
+	"context"
+	"github.com/tyeryan/l-common-util/config"
+)
+
+type LoginGuardConfig struct {
+	WindowInSec            int32 `configdefault:"900" configstruct:"LOGIN_GUARD_CONFIG_WINDOW_IN_SEC" configvalidate:"duration=1s.."`
+	MaxFailuresPerUser     int32 `configdefault:"5" configstruct:"LOGIN_GUARD_CONFIG_MAX_FAILURES_PER_USER" configvalidate:"min=1"`
+	MaxFailuresPerIP       int32 `configdefault:"20" configstruct:"LOGIN_GUARD_CONFIG_MAX_FAILURES_PER_IP" configvalidate:"min=1"`
+	LockoutBaseInSec       int32 `configdefault:"30" configstruct:"LOGIN_GUARD_CONFIG_LOCKOUT_BASE_IN_SEC" configvalidate:"duration=1s.."`
+	LockoutMaxInSec        int32 `configdefault:"3600" configstruct:"LOGIN_GUARD_CONFIG_LOCKOUT_MAX_IN_SEC" configvalidate:"duration=1s.."`
+	SweepUsernameThreshold int32 `configdefault:"10" configstruct:"LOGIN_GUARD_CONFIG_SWEEP_USERNAME_THRESHOLD" configvalidate:"min=1"`
+	// FailOpen lets logins through when redis fails, so an outage doesn't block every login but
+	// also doesn't limit guessing. Without it such attempts are refused for LockoutBaseInSec.
+	FailOpen bool `configdefault:"true" configstruct:"LOGIN_GUARD_CONFIG_FAIL_OPEN"`
+}
+
+func ProvideLoginGuardConfig(ctx context.Context, configStore config.ConfigStore) (*LoginGuardConfig, error) {
+	cnf := LoginGuardConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
+}
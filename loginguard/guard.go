@@ -0,0 +1,179 @@
+package loginguard
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"github.com/google/wire"
+	"github.com/tyeryan/l-common-util/cache"
+	"lake-go/tenant"
+	"lake-go/tracing"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvideLoginGuardConfig,
+		ProvideGuard,
+	)
+)
+
+const (
+	userFailuresKeyPrefix = "lake-go:login-failures:user:"
+	ipFailuresKeyPrefix   = "lake-go:login-failures:ip:"
+	ipUsernamesKeyPrefix  = "lake-go:login-usernames:ip:"
+	userLockKeyPrefix     = "lake-go:login-lock:user:"
+	ipLockKeyPrefix       = "lake-go:login-lock:ip:"
+	ipSweepAlertKeyPrefix = "lake-go:login-sweep-alert:ip:"
+)
+
+// Guard counts failed logins per username and per client ip in sliding windows kept in redis
+// sorted sets, and locks a username or ip out with an exponentially growing lockout.
+type Guard struct {
+	cnf         *LoginGuardConfig
+	cacheClient cache.DistributedCache
+}
+
+func ProvideGuard(ctx context.Context, cnf *LoginGuardConfig, cacheClient cache.DistributedCache) (*Guard, error) {
+	return &Guard{
+		cnf:         cnf,
+		cacheClient: cacheClient,
+	}, nil
+}
+
+// Check returns how long the username or ip is still locked out, zero when the attempt may proceed.
+// Redis errors are logged and, unless FailOpen is off, let the attempt through.
+func (g *Guard) Check(ctx context.Context, username string, ip string) time.Duration {
+	log := tenant.GetLogger(ctx, "loginguard.Check")
+	client := tracing.Redis(ctx, g.cacheClient.GetClient())
+
+	var retryAfter time.Duration
+	for _, key := range []string{userLockKeyPrefix + normalize(username), ipLockKeyPrefix + ip} {
+		ttl, err := client.TTL(key).Result()
+		if err != nil {
+			log.Warne(ctx, "read login lock found error", err)
+			if failed := g.failClosed(); failed > retryAfter {
+				retryAfter = failed
+			}
+			continue
+		}
+		if ttl > retryAfter {
+			retryAfter = ttl
+		}
+	}
+	return retryAfter
+}
+
+// Failed records a failed attempt and locks the username or ip once it crossed its threshold,
+// the returned duration is the lockout which starts now. Redis errors are handled like in Check.
+func (g *Guard) Failed(ctx context.Context, username string, ip string) time.Duration {
+	log := tenant.GetLogger(ctx, "loginguard.Failed")
+	username = normalize(username)
+	now := time.Now()
+
+	userFailures, err := g.record(ctx, userFailuresKeyPrefix+username, strconv.FormatInt(now.UnixNano(), 10), now)
+	if err != nil {
+		log.Warne(ctx, "record login failure found error", err)
+		return g.failClosed()
+	}
+	ipFailures, err := g.record(ctx, ipFailuresKeyPrefix+ip, strconv.FormatInt(now.UnixNano(), 10), now)
+	if err != nil {
+		log.Warne(ctx, "record login failure found error", err)
+		return g.failClosed()
+	}
+	usernames, err := g.record(ctx, ipUsernamesKeyPrefix+ip, username, now)
+	if err != nil {
+		log.Warne(ctx, "record login failure found error", err)
+		return g.failClosed()
+	}
+
+	if usernames >= int64(g.cnf.SweepUsernameThreshold) && g.firstSweepAlert(ctx, ip) {
+		log.Alertw(ctx, "login failures for many usernames from one ip",
+			"ip", ip, "usernames", usernames, "windowInSec", g.cnf.WindowInSec)
+	}
+
+	lockout := g.lock(ctx, userLockKeyPrefix+username, userFailures, int64(g.cnf.MaxFailuresPerUser))
+	if ipLockout := g.lock(ctx, ipLockKeyPrefix+ip, ipFailures, int64(g.cnf.MaxFailuresPerIP)); ipLockout > lockout {
+		lockout = ipLockout
+	}
+	if lockout > 0 {
+		log.Warnw(ctx, "login locked out", "ip", ip, "userFailures", userFailures, "ipFailures", ipFailures, "lockout", lockout)
+	}
+	return lockout
+}
+
+// Succeeded resets the failures of the username, the ip keeps its history so a sweep can't reset itself
+func (g *Guard) Succeeded(ctx context.Context, username string) {
+	err := tracing.Cache(ctx, "del", func() error {
+		return g.cacheClient.Del(userFailuresKeyPrefix + normalize(username))
+	})
+	if err != nil {
+		tenant.GetLogger(ctx, "loginguard.Succeeded").Warne(ctx, "reset login failures found error", err)
+	}
+}
+
+// firstSweepAlert tells whether the sweep from ip wasn't alerted within the window yet. The
+// username count of a sweep may drop below the threshold and cross it again, the alert is sent once
+// either way. A redis error alerts, a repeated alert is better than a missed one.
+func (g *Guard) firstSweepAlert(ctx context.Context, ip string) bool {
+	window := time.Duration(g.cnf.WindowInSec) * time.Second
+	first, err := tracing.Redis(ctx, g.cacheClient.GetClient()).SetNX(ipSweepAlertKeyPrefix+ip, 1, window).Result()
+	if err != nil {
+		tenant.GetLogger(ctx, "loginguard.Failed").Warne(ctx, "set login sweep alert found error", err)
+		return true
+	}
+	return first
+}
+
+// failClosed is the lockout for an attempt redis couldn't check or record
+func (g *Guard) failClosed() time.Duration {
+	if g.cnf.FailOpen {
+		return 0
+	}
+	return time.Duration(g.cnf.LockoutBaseInSec) * time.Second
+}
+
+// record adds member to the sliding window of key and returns the number of members in the window
+func (g *Guard) record(ctx context.Context, key string, member string, now time.Time) (int64, error) {
+	window := time.Duration(g.cnf.WindowInSec) * time.Second
+	var card *redis.IntCmd
+	_, err := tracing.Redis(ctx, g.cacheClient.GetClient()).Pipelined(func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(key, redis.Z{Score: float64(now.UnixNano()), Member: member})
+		pipe.ZRemRangeByScore(key, "-inf", strconv.FormatInt(now.Add(-window).UnixNano(), 10))
+		card = pipe.ZCard(key)
+		pipe.Expire(key, window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return card.Val(), nil
+}
+
+// lock sets a lockout of base * 2^(failures - max), capped at the configured maximum
+func (g *Guard) lock(ctx context.Context, key string, failures int64, max int64) time.Duration {
+	if max <= 0 || failures < max {
+		return 0
+	}
+	base := time.Duration(g.cnf.LockoutBaseInSec) * time.Second
+	limit := time.Duration(g.cnf.LockoutMaxInSec) * time.Second
+	lockout := limit
+	if exp := failures - max; exp < 32 {
+		lockout = time.Duration(math.Min(float64(base)*math.Pow(2, float64(exp)), float64(limit)))
+	}
+
+	err := tracing.Cache(ctx, "set", func() error {
+		return g.cacheClient.Set(key, lockout, true)
+	})
+	if err != nil {
+		tenant.GetLogger(ctx, "loginguard.lock").Warne(ctx, "set login lock found error", err)
+		return 0
+	}
+	return lockout
+}
+
+func normalize(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
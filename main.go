@@ -4,58 +4,150 @@ import (
 	"context"
 	ctxutil "github.com/tyeryan/l-protocol/context"
 	logutil "github.com/tyeryan/l-protocol/log"
+	"lake-go/handler/admin"
+	"lake-go/logging"
+	"lake-go/server"
 	"net/http"
 	"os"
 	"os/signal"
-	"runtime"
+	"path/filepath"
 	"syscall"
 	"time"
 )
 
 func main() {
+	logging.Install()
 	ctx := ctxutil.NewContext(ctxutil.WithStan("main-process"))
 	log := logutil.GetLogger("lake-go")
 
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(ctx, os.Args[1:]))
+	}
+
 	log.Infow(ctx, "starting service lake-go")
 
-	router, err := injectRoutes(ctx)
+	// every invalid key is reported at once instead of the first provider failing
+	configStore, err := injectConfigStore(ctx)
+	if err != nil {
+		log.Fatale(ctx, "load config failed", err)
+	}
+	if err := configStore.Check(knownConfigs()...); err != nil {
+		log.Fatale(ctx, "invalid config", err)
+	}
+
+	svc, err := injectService(ctx)
 	if err != nil {
-		log.Fatale(ctx, "inject router failed", err)
+		log.Fatale(ctx, "inject service failed", err)
 	}
 
-	httpServer := &http.Server{
-		Addr:    ":8080",
-		Handler: router,
+	if svc.migrateConfig.OnStart {
+		migrations, err := svc.migrator.Up(ctx, 0)
+		if err != nil {
+			log.Fatale(ctx, "migrate database failed", err)
+		}
+		log.Infow(ctx, "database migrated", "applied", len(migrations))
+	}
+
+	if err := subscribeConfig(ctx, svc); err != nil {
+		log.Fatale(ctx, "subscribe to config reloads failed", err)
+	}
+	if err := svc.configWatcher.Start(ctx); err != nil {
+		log.Fatale(ctx, "watch config failed", err)
+	}
+
+	httpServer, err := server.NewHTTPServer(ctx, svc.serverConfig, svc.grpcServer.Handler(svc.handler))
+	if err != nil {
+		log.Fatale(ctx, "create http server failed", err)
 	}
 
 	go func() {
-		log.Infow(ctx, "starting http server")
-		if err := httpServer.ListenAndServe(); err != nil {
+		log.Infow(ctx, "starting http server", "addr", svc.serverConfig.Addr, "tls", svc.serverConfig.TLSEnabled())
+		if err := server.ListenAndServe(svc.serverConfig, httpServer); err != nil && err != http.ErrServerClosed {
 			log.Fatale(ctx, "failed to start http server", err)
 		}
 	}()
 
+	var adminServer *http.Server
+	if svc.serverConfig.AdminAddr != "" {
+		adminServer = server.NewAdminServer(svc.serverConfig, svc.adminRoutes)
+		go func() {
+			log.Infow(ctx, "starting admin server", "addr", svc.serverConfig.AdminAddr)
+			if err := adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatale(ctx, "failed to start admin server", err)
+			}
+		}()
+	}
+
 	go func() {
-		termChan := make(chan os.Signal)
+		termChan := make(chan os.Signal, 1)
 		signal.Notify(termChan, syscall.SIGUSR2)
-		stack := make([]byte, 1<<20)
 		for {
 			<-termChan
-			stackLength := runtime.Stack(stack, true)
-			log.Infow(ctx, "stack trace", "dump", string(stack[:stackLength]))
+			file, err := dumpGoroutines()
+			if err != nil {
+				log.Errore(ctx, "dump goroutines found error", err)
+				continue
+			}
+			log.Infow(ctx, "goroutines dumped", "file", file)
 		}
 	}()
 
-	termChan := make(chan os.Signal)
+	go func() {
+		hupChan := make(chan os.Signal, 1)
+		signal.Notify(hupChan, syscall.SIGHUP)
+		for {
+			<-hupChan
+			log.Infow(ctx, "reloading config")
+			// a rejected reload is logged by the watcher and the current config is kept
+			_ = svc.configWatcher.Reload(ctx)
+		}
+	}()
+
+	termChan := make(chan os.Signal, 1)
 	signal.Notify(termChan, syscall.SIGINT, syscall.SIGTERM)
 	log.Infow(ctx, "stopping service", "signal", <-termChan)
+	if err := svc.configWatcher.Close(); err != nil {
+		log.Errore(ctx, "failed to stop config watcher", err)
+	}
+
+	// fail readiness first and keep serving until the load balancer has stopped sending traffic
+	svc.healthRegistry.SetDraining()
+	svc.grpcServer.SetDraining()
+	drainDelay := time.Duration(svc.healthConfig.DrainDelayInSec) * time.Second
+	log.Infow(ctx, "draining http server", "delay", drainDelay)
+	time.Sleep(drainDelay)
 
 	log.Infow(ctx, "stopping http server")
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, svc.serverConfig.ShutdownGrace())
 	defer cancel()
 	if err := httpServer.Shutdown(ctx); err != nil {
 		log.Errore(ctx, "failed to stop http server", err)
 	}
+	svc.grpcServer.Stop()
+	if err := svc.auditRecorder.Close(ctx); err != nil {
+		log.Errore(ctx, "failed to flush audit events", err)
+	}
+	if err := svc.tracing.Shutdown(ctx); err != nil {
+		log.Errore(ctx, "failed to flush traces", err)
+	}
+	// the admin server goes last so the shutdown can still be scraped
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			log.Errore(ctx, "failed to stop admin server", err)
+		}
+	}
 
 	log.Infow(ctx, "stopped service lake-go")
 }
+
+// dumpGoroutines writes the stacks of all goroutines to a file in the temp dir, a log line
+// would truncate them. The same dump is served by GET /admin/goroutines.
+func dumpGoroutines() (string, error) {
+	name := filepath.Join(os.TempDir(), "lake-go-goroutines-"+time.Now().UTC().Format("20060102T150405Z")+".txt")
+	file, err := os.Create(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return name, admin.DumpGoroutines(file)
+}
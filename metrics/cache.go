@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tyeryan/l-common-util/cache"
+	"sync"
+	"time"
+)
+
+const pipelineCommand = "pipeline"
+
+// cacheClient counts hits, misses and latency of the DistributedCache calls, and instruments the
+// redis client behind it for the stores which run their commands on GetClient directly
+type cacheClient struct {
+	cache.DistributedCache
+	requests        *prometheus.CounterVec
+	duration        *prometheus.HistogramVec
+	commands        *prometheus.CounterVec
+	commandDuration *prometheus.HistogramVec
+
+	mu sync.Mutex
+	// hooked the redis client which got the process hooks, the cache client replaces it after timeouts
+	hooked *redis.ClusterClient
+}
+
+// ProvideCacheClient replaces cache.ProvideCacheClient, the returned cache is the one of
+// l-common-util with metrics
+func ProvideCacheClient(ctx context.Context,
+	redisClient *redis.ClusterClient,
+	cnf *cache.RedisConfig,
+	registry *prometheus.Registry) (cache.DistributedCache, error) {
+	distributedCache, err := cache.ProvideCacheClient(ctx, redisClient, cnf)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &cacheClient{
+		DistributedCache: distributedCache,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "requests_total",
+			Help:      "Distributed cache calls by operation and result, get results are hit, miss or error.",
+		}, []string{"operation", "result"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "request_duration_seconds",
+			Help:      "Distributed cache call latency by operation.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation"}),
+		commands: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "redis",
+			Name:      "commands_total",
+			Help:      "Redis commands by command name and result, pipelines count once.",
+		}, []string{"command", "result"}),
+		commandDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "redis",
+			Name:      "command_duration_seconds",
+			Help:      "Redis command latency by command name.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"command"}),
+	}
+	for _, collector := range []prometheus.Collector{c.requests, c.duration, c.commands, c.commandDuration} {
+		if err := registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	c.GetClient()
+	return c, nil
+}
+
+func (c *cacheClient) Get(key string, v interface{}) error {
+	start := time.Now()
+	err := c.DistributedCache.Get(key, v)
+	result := "hit"
+	if err == redis.Nil {
+		result = "miss"
+	} else if err != nil {
+		result = "error"
+	}
+	c.observe("get", result, start)
+	return err
+}
+
+func (c *cacheClient) Set(key string, expiration time.Duration, v interface{}) error {
+	start := time.Now()
+	err := c.DistributedCache.Set(key, expiration, v)
+	c.observe("set", okOrError(err), start)
+	return err
+}
+
+func (c *cacheClient) Del(key string) error {
+	start := time.Now()
+	err := c.DistributedCache.Del(key)
+	c.observe("del", okOrError(err), start)
+	return err
+}
+
+// GetClient hooks the metrics into the current redis client before handing it out
+func (c *cacheClient) GetClient() *redis.ClusterClient {
+	client := c.DistributedCache.GetClient()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client != nil && client != c.hooked {
+		client.WrapProcess(c.wrapProcess)
+		client.WrapProcessPipeline(c.wrapProcessPipeline)
+		c.hooked = client
+	}
+	return client
+}
+
+func (c *cacheClient) observe(operation string, result string, start time.Time) {
+	c.requests.WithLabelValues(operation, result).Inc()
+	c.duration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func (c *cacheClient) wrapProcess(process func(redis.Cmder) error) func(redis.Cmder) error {
+	return func(cmd redis.Cmder) error {
+		start := time.Now()
+		err := process(cmd)
+		c.observeCommand(cmd.Name(), err, start)
+		return err
+	}
+}
+
+func (c *cacheClient) wrapProcessPipeline(process func([]redis.Cmder) error) func([]redis.Cmder) error {
+	return func(cmds []redis.Cmder) error {
+		start := time.Now()
+		err := process(cmds)
+		c.observeCommand(pipelineCommand, err, start)
+		return err
+	}
+}
+
+func (c *cacheClient) observeCommand(command string, err error, start time.Time) {
+	// a missing key is an answer, not a failure
+	if err == redis.Nil {
+		err = nil
+	}
+	c.commands.WithLabelValues(command, okOrError(err)).Inc()
+	c.commandDuration.WithLabelValues(command).Observe(time.Since(start).Seconds())
+}
+
+func okOrError(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
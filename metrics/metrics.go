@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"context"
+	"github.com/google/wire"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvideRegistry,
+		ProvideHTTPMetrics,
+		ProvideGRPCClientMetrics,
+		ProvideCacheClient,
+	)
+)
+
+const namespace = "lake"
+
+// ProvideRegistry the registry behind /metrics, it starts with the go runtime and process collectors.
+// A registry of our own keeps metrics of vendored libraries which use the default one out of it.
+func ProvideRegistry(ctx context.Context) (*prometheus.Registry, error) {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(collectors.NewGoCollector()); err != nil {
+		return nil, err
+	}
+	if err := registry.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// Handler serves the registry in the prometheus exposition format
+func Handler(registry *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
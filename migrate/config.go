@@ -0,0 +1,26 @@
+package migrate
+
+import (
+	"context"
+	"github.com/tyeryan/l-common-util/config"
+	"time"
+)
+
+type MigrateConfig struct {
+	// OnStart applies pending migrations before the service starts serving
+	OnStart bool `configdefault:"false" configstruct:"MIGRATE_CONFIG_ON_START"`
+	// LockTimeoutInSec is how long a replica waits for another one to finish migrating
+	LockTimeoutInSec int32 `configdefault:"300" configstruct:"MIGRATE_CONFIG_LOCK_TIMEOUT_IN_SEC" configvalidate:"duration=1s.."`
+}
+
+func ProvideMigrateConfig(ctx context.Context, configStore config.ConfigStore) (*MigrateConfig, error) {
+	cnf := MigrateConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
+}
+
+func (cnf *MigrateConfig) lockTimeout() time.Duration {
+	return time.Duration(cnf.LockTimeoutInSec) * time.Second
+}
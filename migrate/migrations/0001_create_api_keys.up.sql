@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+	id           BIGSERIAL PRIMARY KEY,
+	prefix       TEXT        NOT NULL UNIQUE,
+	secret_hash  TEXT        NOT NULL,
+	name         TEXT        NOT NULL,
+	owner_id     TEXT        NOT NULL,
+	scopes       TEXT[]      NOT NULL DEFAULT '{}',
+	created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires_at   TIMESTAMPTZ,
+	last_used_at TIMESTAMPTZ,
+	revoked_at   TIMESTAMPTZ
+);
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+	id          BIGSERIAL PRIMARY KEY,
+	occurred_at TIMESTAMPTZ NOT NULL,
+	tenant      TEXT        NOT NULL DEFAULT '',
+	actor       TEXT        NOT NULL,
+	stan        TEXT        NOT NULL DEFAULT '',
+	action      TEXT        NOT NULL,
+	resource    TEXT        NOT NULL,
+	outcome     TEXT        NOT NULL,
+	detail      JSONB,
+	prev_hash   TEXT        NOT NULL,
+	hash        TEXT        NOT NULL UNIQUE
+);
+CREATE INDEX IF NOT EXISTS audit_events_tenant_occurred_at ON audit_events (tenant, occurred_at);
+CREATE INDEX IF NOT EXISTS audit_events_actor ON audit_events (actor);
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append only';
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events
+	FOR EACH ROW EXECUTE PROCEDURE audit_events_append_only();
+DROP TRIGGER IF EXISTS audit_events_no_truncate ON audit_events;
+CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events
+	FOR EACH STATEMENT EXECUTE PROCEDURE audit_events_append_only();
@@ -0,0 +1,240 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/google/wire"
+	"lake-go/tenant"
+	"time"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvideMigrateConfig,
+		ProvideMigrator,
+	)
+
+	ErrIrreversible = errors.New("migration has no down script")
+	ErrModified     = errors.New("migration changed after it was applied")
+)
+
+const (
+	createTableSQL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    BIGINT PRIMARY KEY,
+	name       TEXT        NOT NULL,
+	checksum   TEXT        NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+	// lockID is the advisory lock replicas take around migrating
+	lockID = 0x6c616b656d696772
+)
+
+// Migrator applies the embedded migrations, every migration runs in its own transaction
+// together with its schema_migrations row
+type Migrator struct {
+	cnf        *MigrateConfig
+	db         *sql.DB
+	migrations []*Migration
+}
+
+// Status of one migration, AppliedAt is nil while it is pending
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	// Modified the script changed after it was applied
+	Modified bool
+	// Missing the migration was applied but is unknown to this build
+	Missing bool
+}
+
+type applied struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+func ProvideMigrator(ctx context.Context, cnf *MigrateConfig, db *sql.DB) (*Migrator, error) {
+	migrations, err := load(embedded, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		cnf:        cnf,
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Up applies up to steps pending migrations in order, all of them when steps is 0. Nothing is
+// applied when a script changed after it was applied, the schema may not be what this build expects.
+func (m *Migrator) Up(ctx context.Context, steps int) ([]*Migration, error) {
+	var done []*Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if a, ok := applied[migration.Version]; ok && a.checksum != migration.Checksum() {
+				return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, ErrModified)
+			}
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if steps > 0 && len(done) == steps {
+				break
+			}
+			if err := m.run(ctx, conn, migration, "up", migration.Up,
+				`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+				migration.Version, migration.Name, migration.Checksum()); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the last steps applied migrations, newest first
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	var done []*Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, ErrIrreversible)
+			}
+			if err := m.run(ctx, conn, migration, "down", migration.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`, migration.Version); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists the known migrations in order followed by applied ones this build doesn't know
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, createTableSQL); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []*Status{}
+	for _, migration := range m.migrations {
+		status := &Status{Version: migration.Version, Name: migration.Name}
+		if a, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &a.appliedAt
+			status.Modified = a.checksum != migration.Checksum()
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for version, a := range applied {
+		appliedAt := a.appliedAt
+		statuses = append(statuses, &Status{Version: version, Name: a.name, AppliedAt: &appliedAt, Missing: true})
+	}
+	return statuses, nil
+}
+
+// locked runs fn on one connection holding the advisory lock, replicas starting at the same
+// time wait for each other and then find the migrations applied
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	log := tenant.GetLogger(ctx, "Migrator")
+
+	lockCtx, cancel := context.WithTimeout(ctx, m.cnf.lockTimeout())
+	defer cancel()
+	conn, err := m.db.Conn(lockCtx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(lockCtx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("wait for the migration lock: %w", err)
+	}
+	defer func() {
+		// a fresh context, the lock must be released even when ctx is done
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(unlockCtx, `SELECT pg_advisory_unlock($1)`, lockID); err != nil {
+			log.Errore(ctx, "release migration lock found error", err)
+			// the session keeps the lock, don't hand the connection back to the pool
+			conn.Raw(func(driverConn interface{}) error { return driver.ErrBadConn })
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createTableSQL); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]*applied, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := map[int64]*applied{}
+	for rows.Next() {
+		var version int64
+		a := &applied{}
+		if err := rows.Scan(&version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		versions[version] = a
+	}
+	return versions, rows.Err()
+}
+
+func (m *Migrator) run(ctx context.Context, conn *sql.Conn, migration *Migration, direction string, script string, record string, args ...interface{}) error {
+	log := tenant.GetLogger(ctx, "Migrator")
+	start := time.Now()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// no arguments, so lib/pq sends the script as a simple query which may hold several statements
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Infow(ctx, "migration done", "version", migration.Version, "name", migration.Name, "direction", direction,
+		"durationMs", time.Since(start).Milliseconds())
+	return nil
+}
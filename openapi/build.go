@@ -0,0 +1,206 @@
+package openapi
+
+import (
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"lake-go/responder"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	Version = "3.1.0"
+
+	securityBearer = "bearerAuth"
+	securityAPIKey = "apiKeyAuth"
+)
+
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// Operation describes one route of the router, every route needs one so the document
+// can't silently fall behind the router
+type Operation struct {
+	Method  string
+	Route   string
+	ID      string
+	Summary string
+	Tags    []string
+	// Public operations don't require a bearer token or an api key
+	Public bool
+	// Hidden operations are covered but left out of the document, like the swagger ui assets
+	Hidden bool
+	// MediaTypes of the request and response bodies, as allowed by responder.Negotiate
+	MediaTypes []string
+	Query      []*Parameter
+	Request    interface{}
+	// Responses by status, a nil value is a response without body
+	Responses map[int]interface{}
+	// StreamItem is the schema of one line when the operation streams NDJSON
+	StreamItem interface{}
+}
+
+// Build documents the operations. The error lists the routes without operation and the operations
+// without route, the document is returned either way so a mismatch doesn't keep the service down.
+func Build(info *Info, routes chi.Routes, operations []*Operation) (*Document, error) {
+	routed := map[string]bool{}
+	err := chi.Walk(routes, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		routed[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	doc := document(info, operations)
+
+	documented := map[string]bool{}
+	for _, op := range operations {
+		documented[op.Method+" "+op.Route] = true
+	}
+	var missing, stale []string
+	for key := range routed {
+		if !documented[key] {
+			missing = append(missing, key)
+		}
+	}
+	for key := range documented {
+		if !routed[key] {
+			stale = append(stale, key)
+		}
+	}
+	if len(missing) > 0 || len(stale) > 0 {
+		sort.Strings(missing)
+		sort.Strings(stale)
+		return doc, fmt.Errorf("openapi operations out of sync with the router, undocumented routes %v, operations without route %v", missing, stale)
+	}
+	return doc, nil
+}
+
+func document(info *Info, operations []*Operation) *Document {
+	s := newSchemas()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]*PathItem{},
+		Components: &Components{
+			Schemas: s.components,
+			SecuritySchemes: map[string]*SecurityScheme{
+				securityBearer: {Type: "http", Scheme: "bearer"},
+				securityAPIKey: {Type: "apiKey", Name: "X-Api-Key", In: "header"},
+			},
+		},
+	}
+	for _, op := range operations {
+		if op.Hidden {
+			continue
+		}
+		path := pathParam.ReplaceAllString(op.Route, "{$1}")
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		(*item)[strings.ToLower(op.Method)] = op.build(s)
+	}
+	return doc
+}
+
+func (op *Operation) build(s *schemas) *OperationObject {
+	mediaTypes := op.MediaTypes
+	if len(mediaTypes) == 0 {
+		mediaTypes = []string{responder.MediaTypeJSON}
+	}
+
+	obj := &OperationObject{
+		OperationID: op.ID,
+		Summary:     op.Summary,
+		Tags:        op.Tags,
+		Responses:   map[string]*Response{},
+	}
+	if obj.OperationID == "" {
+		obj.OperationID = operationID(op.Method, op.Route)
+	}
+
+	for _, match := range pathParam.FindAllStringSubmatch(op.Route, -1) {
+		obj.Parameters = append(obj.Parameters, &Parameter{Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, param := range op.Query {
+		p := *param
+		p.In = "query"
+		if p.Schema == nil {
+			p.Schema = &Schema{Type: "string"}
+		}
+		obj.Parameters = append(obj.Parameters, &p)
+	}
+
+	if op.Request != nil {
+		obj.RequestBody = &RequestBody{Required: true, Content: content(s, mediaTypes, op.Request, nil)}
+		obj.Responses["415"] = problem(s, "request body in an unsupported content type")
+	}
+	if op.Request != nil || len(op.Query) > 0 || len(obj.Parameters) > 0 {
+		obj.Responses["400"] = problem(s, "invalid request")
+	}
+	if len(op.MediaTypes) > 0 {
+		obj.Responses["406"] = problem(s, "none of the acceptable content types can be produced")
+	}
+	if !op.Public {
+		obj.Security = []map[string][]string{{securityBearer: {}}, {securityAPIKey: {}}}
+		obj.Responses["401"] = problem(s, "missing or invalid credentials")
+		obj.Responses["403"] = problem(s, "denied by the authorization policy")
+		obj.Responses["429"] = problem(s, "rate limit exceeded")
+		obj.Responses["429"].Headers = map[string]*Header{
+			"Retry-After": {Description: "seconds until the request may be retried", Schema: &Schema{Type: "integer"}},
+		}
+	}
+	obj.Responses["default"] = problem(s, "error")
+
+	for status, body := range op.Responses {
+		rsp := &Response{Description: http.StatusText(status)}
+		if body != nil {
+			rsp.Content = content(s, mediaTypes, body, op.StreamItem)
+		}
+		obj.Responses[strconv.Itoa(status)] = rsp
+	}
+	return obj
+}
+
+func content(s *schemas, mediaTypes []string, body interface{}, streamItem interface{}) map[string]*MediaType {
+	schema := s.of(body)
+	c := map[string]*MediaType{}
+	for _, mediaType := range mediaTypes {
+		if mediaType == responder.MediaTypeNDJSON {
+			if streamItem != nil {
+				c[mediaType] = &MediaType{Schema: s.of(streamItem)}
+			}
+			continue
+		}
+		c[mediaType] = &MediaType{Schema: schema}
+	}
+	return c
+}
+
+func problem(s *schemas, description string) *Response {
+	return &Response{
+		Description: description,
+		Content: map[string]*MediaType{
+			"application/problem+json": {Schema: s.of(&responder.Problem{})},
+		},
+	}
+}
+
+// operationID derives an id like getAuthSessionsId from the method and route
+func operationID(method string, route string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(pathParam.ReplaceAllString(route, "$1"), func(r rune) bool {
+		return r == '/' || r == '-' || r == '_' || r == '.' || r == '*'
+	}) {
+		if part == "v1" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	swaggerFiles "github.com/swaggo/files/v2"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+const swaggerIndex = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="UTF-8">
+  <title>%[1]s</title>
+  <link rel="stylesheet" type="text/css" href="swagger-ui.css">
+  <link rel="icon" type="image/png" href="favicon-32x32.png" sizes="32x32">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="swagger-ui-bundle.js"></script>
+  <script src="swagger-ui-standalone-preset.js"></script>
+  <script>
+    window.onload = function () {
+      window.ui = SwaggerUIBundle({
+        url: "%[2]s",
+        dom_id: "#swagger-ui",
+        deepLinking: true,
+        presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+        layout: "StandaloneLayout"
+      });
+    };
+  </script>
+</body>
+</html>
+`
+
+// Handler serves the document, it is marshalled once as the routes don't change at runtime
+func Handler(doc *Document) (http.Handler, error) {
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write(buf)
+	}
+	return http.HandlerFunc(fn), nil
+}
+
+// SwaggerUI serves the bundled swagger ui under prefix, it needs no internet access
+func SwaggerUI(prefix string, title string, specURL string) http.Handler {
+	index := []byte(fmt.Sprintf(swaggerIndex, title, specURL))
+	assets := http.StripPrefix(prefix, http.FileServer(http.FS(swaggerFiles.FS)))
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, prefix)
+		if name == "" || name == "index.html" {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write(index)
+			return
+		}
+		if _, err := fs.Stat(swaggerFiles.FS, name); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		assets.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
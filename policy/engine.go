@@ -0,0 +1,97 @@
+package policy
+
+import (
+	"context"
+	"github.com/google/wire"
+	"github.com/tyeryan/l-common-util/config"
+	logutil "github.com/tyeryan/l-protocol/log"
+	"os"
+	"sync"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvidePolicyConfig,
+		ProvideEngine,
+	)
+)
+
+type PolicyConfig struct {
+	// File yaml or json policy, without it every authenticated request is allowed except the
+	// admin routes, which require the admin role. Edits of the file apply on config reloads.
+	File string `configstruct:"POLICY_CONFIG_FILE"`
+}
+
+// Engine evaluates requests against the current policy
+type Engine struct {
+	mutex  sync.RWMutex
+	policy *Policy
+}
+
+func ProvidePolicyConfig(ctx context.Context, configStore config.ConfigStore) (*PolicyConfig, error) {
+	cnf := PolicyConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
+}
+
+func ProvideEngine(ctx context.Context, cnf *PolicyConfig) (*Engine, error) {
+	log := logutil.GetLogger("ProvideEngine")
+
+	engine := &Engine{}
+	if cnf.File == "" {
+		log.Warnw(ctx, "no policy file configured, all authenticated requests are allowed except admin routes")
+		engine.policy = defaultPolicy()
+		return engine, nil
+	}
+
+	if err := engine.LoadFile(ctx, cnf.File); err != nil {
+		log.Errore(ctx, "load policy found error", err, "file", cnf.File)
+		return nil, err
+	}
+	return engine, nil
+}
+
+// LoadFile replaces the current policy with the one in file, the current policy is kept on error
+func (e *Engine) LoadFile(ctx context.Context, file string) error {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	p, err := Parse(raw)
+	if err != nil {
+		return err
+	}
+
+	e.mutex.Lock()
+	e.policy = p
+	e.mutex.Unlock()
+
+	logutil.GetLogger("Engine").Infow(ctx, "policy loaded", "file", file, "rules", len(p.Rules), "roles", len(p.Roles))
+	return nil
+}
+
+// Load parses the content of a policy file, without a file the default policy applies
+func Load(raw []byte) (*Policy, error) {
+	if raw == nil {
+		return defaultPolicy(), nil
+	}
+	return Parse(raw)
+}
+
+// Apply replaces the current policy, it is subscribed to config reloads
+func (e *Engine) Apply(p *Policy) {
+	e.mutex.Lock()
+	e.policy = p
+	e.mutex.Unlock()
+
+	logutil.GetLogger("Engine").Infow(context.Background(), "policy applied", "rules", len(p.Rules), "roles", len(p.Roles))
+}
+
+func (e *Engine) Evaluate(method string, route string, roles []string) *Decision {
+	e.mutex.RLock()
+	p := e.policy
+	e.mutex.RUnlock()
+	return p.Evaluate(method, route, roles)
+}
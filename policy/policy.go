@@ -0,0 +1,194 @@
+package policy
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"path"
+	"strings"
+)
+
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+
+	// AdminRole is granted every permission by the policy used without a policy file
+	AdminRole = "admin"
+
+	wildcard = "*"
+)
+
+// Policy is the declarative authorization policy, it is read from yaml or json
+//
+//	default: deny
+//	roles:
+//	  admin: ["*"]
+//	  analyst: ["dataset:read", "auth:session:*"]
+//	rules:
+//	  - route: /v1/auth/sessions
+//	    methods: [GET]
+//	    permissions: [auth:session:read]
+//	  - route: /v1/admin/*
+//	    effect: deny
+//	    roles: [analyst]
+type Policy struct {
+	// Default is the effect for routes no allow rule matches
+	Default string `yaml:"default" json:"default"`
+	// Roles maps a role to the permissions it grants, "*" and "prefix:*" are wildcards
+	Roles map[string][]string `yaml:"roles" json:"roles"`
+	Rules []*Rule             `yaml:"rules" json:"rules"`
+}
+
+// Rule matches chi route patterns and methods. Allow rules require all their permissions,
+// deny rules reject the listed roles ("*" for everybody) regardless of permissions.
+type Rule struct {
+	Name        string   `yaml:"name" json:"name"`
+	Route       string   `yaml:"route" json:"route"`
+	Methods     []string `yaml:"methods" json:"methods"`
+	Effect      string   `yaml:"effect" json:"effect"`
+	Permissions []string `yaml:"permissions" json:"permissions"`
+	Roles       []string `yaml:"roles" json:"roles"`
+}
+
+// Decision is the outcome of an evaluation, Rule is the rule which decided it
+// and is nil when the policy default applied.
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Rule    *Rule  `json:"rule,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// defaultPolicy allows every authenticated request except the admin routes and the grpc admin
+// service, which only the admin role may call
+func defaultPolicy() *Policy {
+	return &Policy{
+		Default: EffectAllow,
+		Roles:   map[string][]string{AdminRole: {wildcard}},
+		Rules: []*Rule{
+			{Name: "admin", Route: "/v1/admin/*", Effect: EffectAllow, Permissions: []string{"admin"}},
+			{Name: "grpc-admin", Route: "/lake.LakeAdmin/*", Effect: EffectAllow, Permissions: []string{"admin"}},
+		},
+	}
+}
+
+// Parse reads a policy from yaml, json documents are accepted as well
+func Parse(raw []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := yaml.Unmarshal(raw, p); err != nil {
+		return nil, err
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Policy) validate() error {
+	if p.Default == "" {
+		p.Default = EffectDeny
+	}
+	if p.Default != EffectAllow && p.Default != EffectDeny {
+		return fmt.Errorf("invalid default effect %q", p.Default)
+	}
+	for i, rule := range p.Rules {
+		if rule.Route == "" {
+			return fmt.Errorf("rule %d has no route", i)
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rules[%d]", i)
+		}
+		if rule.Effect == "" {
+			rule.Effect = EffectAllow
+		}
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return fmt.Errorf("rule %s has invalid effect %q", rule.Name, rule.Effect)
+		}
+		if rule.Effect == EffectDeny && len(rule.Roles) == 0 {
+			return fmt.Errorf("deny rule %s has no roles", rule.Name)
+		}
+		if _, err := path.Match(strings.TrimSuffix(rule.Route, wildcard), ""); err != nil {
+			return fmt.Errorf("rule %s has invalid route %q: %w", rule.Name, rule.Route, err)
+		}
+	}
+	return nil
+}
+
+// Evaluate decides whether a caller with the given roles may call method on the chi route pattern
+func (p *Policy) Evaluate(method string, route string, roles []string) *Decision {
+	var allowRules []*Rule
+	for _, rule := range p.Rules {
+		if !rule.matches(method, route) {
+			continue
+		}
+		if rule.Effect == EffectDeny {
+			if intersects(rule.Roles, roles) {
+				return &Decision{Allowed: false, Rule: rule, Reason: "denied by rule"}
+			}
+			continue
+		}
+		allowRules = append(allowRules, rule)
+	}
+
+	if len(allowRules) == 0 {
+		return &Decision{Allowed: p.Default == EffectAllow, Reason: "policy default " + p.Default}
+	}
+
+	granted := p.permissions(roles)
+	for _, rule := range allowRules {
+		for _, required := range rule.Permissions {
+			if !grants(granted, required) {
+				return &Decision{Allowed: false, Rule: rule, Reason: "missing permission " + required}
+			}
+		}
+	}
+	return &Decision{Allowed: true, Rule: allowRules[0], Reason: "allowed by rule"}
+}
+
+func (p *Policy) permissions(roles []string) []string {
+	var granted []string
+	for _, role := range roles {
+		granted = append(granted, p.Roles[role]...)
+	}
+	return granted
+}
+
+func (r *Rule) matches(method string, route string) bool {
+	if len(r.Methods) > 0 && !containsFold(r.Methods, method) && !containsFold(r.Methods, wildcard) {
+		return false
+	}
+	if strings.HasSuffix(r.Route, wildcard) {
+		return strings.HasPrefix(route, strings.TrimSuffix(r.Route, wildcard))
+	}
+	matched, _ := path.Match(r.Route, route)
+	return matched
+}
+
+// grants checks the required permission against granted patterns like "*" or "dataset:*"
+func grants(granted []string, required string) bool {
+	for _, g := range granted {
+		if g == wildcard || g == required {
+			return true
+		}
+		if strings.HasSuffix(g, ":"+wildcard) && strings.HasPrefix(required, strings.TrimSuffix(g, wildcard)) {
+			return true
+		}
+	}
+	return false
+}
+
+func intersects(ruleRoles []string, roles []string) bool {
+	for _, r := range ruleRoles {
+		if r == wildcard || containsFold(roles, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, x string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, x) {
+			return true
+		}
+	}
+	return false
+}
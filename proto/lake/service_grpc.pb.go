@@ -0,0 +1,424 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: lake/service.proto
+
+package lake
+
+import (
+	context "context"
+	lauth "github.com/tyeryan/l-protocol/go/lauth"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	LakeAuth_Login_FullMethodName         = "/lake.LakeAuth/Login"
+	LakeAuth_Logout_FullMethodName        = "/lake.LakeAuth/Logout"
+	LakeAuth_ListSessions_FullMethodName  = "/lake.LakeAuth/ListSessions"
+	LakeAuth_RevokeSession_FullMethodName = "/lake.LakeAuth/RevokeSession"
+	LakeAuth_CreateApiKey_FullMethodName  = "/lake.LakeAuth/CreateApiKey"
+	LakeAuth_ListApiKeys_FullMethodName   = "/lake.LakeAuth/ListApiKeys"
+	LakeAuth_RevokeApiKey_FullMethodName  = "/lake.LakeAuth/RevokeApiKey"
+)
+
+// LakeAuthClient is the client API for LakeAuth service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LakeAuthClient interface {
+	// Login is the only call which doesn't require credentials
+	Login(ctx context.Context, in *lauth.AuthReq, opts ...grpc.CallOption) (*lauth.AuthRsp, error)
+	Logout(ctx context.Context, in *LogoutReq, opts ...grpc.CallOption) (*LogoutRsp, error)
+	ListSessions(ctx context.Context, in *ListSessionsReq, opts ...grpc.CallOption) (*ListSessionsRsp, error)
+	RevokeSession(ctx context.Context, in *RevokeSessionReq, opts ...grpc.CallOption) (*RevokeSessionRsp, error)
+	CreateApiKey(ctx context.Context, in *CreateApiKeyReq, opts ...grpc.CallOption) (*CreateApiKeyRsp, error)
+	ListApiKeys(ctx context.Context, in *ListApiKeysReq, opts ...grpc.CallOption) (*ListApiKeysRsp, error)
+	RevokeApiKey(ctx context.Context, in *RevokeApiKeyReq, opts ...grpc.CallOption) (*RevokeApiKeyRsp, error)
+}
+
+type lakeAuthClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLakeAuthClient(cc grpc.ClientConnInterface) LakeAuthClient {
+	return &lakeAuthClient{cc}
+}
+
+func (c *lakeAuthClient) Login(ctx context.Context, in *lauth.AuthReq, opts ...grpc.CallOption) (*lauth.AuthRsp, error) {
+	out := new(lauth.AuthRsp)
+	err := c.cc.Invoke(ctx, LakeAuth_Login_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lakeAuthClient) Logout(ctx context.Context, in *LogoutReq, opts ...grpc.CallOption) (*LogoutRsp, error) {
+	out := new(LogoutRsp)
+	err := c.cc.Invoke(ctx, LakeAuth_Logout_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lakeAuthClient) ListSessions(ctx context.Context, in *ListSessionsReq, opts ...grpc.CallOption) (*ListSessionsRsp, error) {
+	out := new(ListSessionsRsp)
+	err := c.cc.Invoke(ctx, LakeAuth_ListSessions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lakeAuthClient) RevokeSession(ctx context.Context, in *RevokeSessionReq, opts ...grpc.CallOption) (*RevokeSessionRsp, error) {
+	out := new(RevokeSessionRsp)
+	err := c.cc.Invoke(ctx, LakeAuth_RevokeSession_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lakeAuthClient) CreateApiKey(ctx context.Context, in *CreateApiKeyReq, opts ...grpc.CallOption) (*CreateApiKeyRsp, error) {
+	out := new(CreateApiKeyRsp)
+	err := c.cc.Invoke(ctx, LakeAuth_CreateApiKey_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lakeAuthClient) ListApiKeys(ctx context.Context, in *ListApiKeysReq, opts ...grpc.CallOption) (*ListApiKeysRsp, error) {
+	out := new(ListApiKeysRsp)
+	err := c.cc.Invoke(ctx, LakeAuth_ListApiKeys_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lakeAuthClient) RevokeApiKey(ctx context.Context, in *RevokeApiKeyReq, opts ...grpc.CallOption) (*RevokeApiKeyRsp, error) {
+	out := new(RevokeApiKeyRsp)
+	err := c.cc.Invoke(ctx, LakeAuth_RevokeApiKey_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LakeAuthServer is the server API for LakeAuth service.
+// All implementations must embed UnimplementedLakeAuthServer
+// for forward compatibility
+type LakeAuthServer interface {
+	// Login is the only call which doesn't require credentials
+	Login(context.Context, *lauth.AuthReq) (*lauth.AuthRsp, error)
+	Logout(context.Context, *LogoutReq) (*LogoutRsp, error)
+	ListSessions(context.Context, *ListSessionsReq) (*ListSessionsRsp, error)
+	RevokeSession(context.Context, *RevokeSessionReq) (*RevokeSessionRsp, error)
+	CreateApiKey(context.Context, *CreateApiKeyReq) (*CreateApiKeyRsp, error)
+	ListApiKeys(context.Context, *ListApiKeysReq) (*ListApiKeysRsp, error)
+	RevokeApiKey(context.Context, *RevokeApiKeyReq) (*RevokeApiKeyRsp, error)
+	mustEmbedUnimplementedLakeAuthServer()
+}
+
+// UnimplementedLakeAuthServer must be embedded to have forward compatible implementations.
+type UnimplementedLakeAuthServer struct {
+}
+
+func (UnimplementedLakeAuthServer) Login(context.Context, *lauth.AuthReq) (*lauth.AuthRsp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (UnimplementedLakeAuthServer) Logout(context.Context, *LogoutReq) (*LogoutRsp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Logout not implemented")
+}
+func (UnimplementedLakeAuthServer) ListSessions(context.Context, *ListSessionsReq) (*ListSessionsRsp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSessions not implemented")
+}
+func (UnimplementedLakeAuthServer) RevokeSession(context.Context, *RevokeSessionReq) (*RevokeSessionRsp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeSession not implemented")
+}
+func (UnimplementedLakeAuthServer) CreateApiKey(context.Context, *CreateApiKeyReq) (*CreateApiKeyRsp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateApiKey not implemented")
+}
+func (UnimplementedLakeAuthServer) ListApiKeys(context.Context, *ListApiKeysReq) (*ListApiKeysRsp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListApiKeys not implemented")
+}
+func (UnimplementedLakeAuthServer) RevokeApiKey(context.Context, *RevokeApiKeyReq) (*RevokeApiKeyRsp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeApiKey not implemented")
+}
+func (UnimplementedLakeAuthServer) mustEmbedUnimplementedLakeAuthServer() {}
+
+// UnsafeLakeAuthServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LakeAuthServer will
+// result in compilation errors.
+type UnsafeLakeAuthServer interface {
+	mustEmbedUnimplementedLakeAuthServer()
+}
+
+func RegisterLakeAuthServer(s grpc.ServiceRegistrar, srv LakeAuthServer) {
+	s.RegisterService(&LakeAuth_ServiceDesc, srv)
+}
+
+func _LakeAuth_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(lauth.AuthReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LakeAuthServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LakeAuth_Login_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LakeAuthServer).Login(ctx, req.(*lauth.AuthReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LakeAuth_Logout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogoutReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LakeAuthServer).Logout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LakeAuth_Logout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LakeAuthServer).Logout(ctx, req.(*LogoutReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LakeAuth_ListSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSessionsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LakeAuthServer).ListSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LakeAuth_ListSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LakeAuthServer).ListSessions(ctx, req.(*ListSessionsReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LakeAuth_RevokeSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeSessionReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LakeAuthServer).RevokeSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LakeAuth_RevokeSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LakeAuthServer).RevokeSession(ctx, req.(*RevokeSessionReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LakeAuth_CreateApiKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateApiKeyReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LakeAuthServer).CreateApiKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LakeAuth_CreateApiKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LakeAuthServer).CreateApiKey(ctx, req.(*CreateApiKeyReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LakeAuth_ListApiKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListApiKeysReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LakeAuthServer).ListApiKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LakeAuth_ListApiKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LakeAuthServer).ListApiKeys(ctx, req.(*ListApiKeysReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LakeAuth_RevokeApiKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeApiKeyReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LakeAuthServer).RevokeApiKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LakeAuth_RevokeApiKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LakeAuthServer).RevokeApiKey(ctx, req.(*RevokeApiKeyReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// LakeAuth_ServiceDesc is the grpc.ServiceDesc for LakeAuth service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LakeAuth_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "lake.LakeAuth",
+	HandlerType: (*LakeAuthServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Login",
+			Handler:    _LakeAuth_Login_Handler,
+		},
+		{
+			MethodName: "Logout",
+			Handler:    _LakeAuth_Logout_Handler,
+		},
+		{
+			MethodName: "ListSessions",
+			Handler:    _LakeAuth_ListSessions_Handler,
+		},
+		{
+			MethodName: "RevokeSession",
+			Handler:    _LakeAuth_RevokeSession_Handler,
+		},
+		{
+			MethodName: "CreateApiKey",
+			Handler:    _LakeAuth_CreateApiKey_Handler,
+		},
+		{
+			MethodName: "ListApiKeys",
+			Handler:    _LakeAuth_ListApiKeys_Handler,
+		},
+		{
+			MethodName: "RevokeApiKey",
+			Handler:    _LakeAuth_RevokeApiKey_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "lake/service.proto",
+}
+
+const (
+	LakeAdmin_CheckPolicy_FullMethodName = "/lake.LakeAdmin/CheckPolicy"
+)
+
+// LakeAdminClient is the client API for LakeAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LakeAdminClient interface {
+	CheckPolicy(ctx context.Context, in *CheckPolicyReq, opts ...grpc.CallOption) (*CheckPolicyRsp, error)
+}
+
+type lakeAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLakeAdminClient(cc grpc.ClientConnInterface) LakeAdminClient {
+	return &lakeAdminClient{cc}
+}
+
+func (c *lakeAdminClient) CheckPolicy(ctx context.Context, in *CheckPolicyReq, opts ...grpc.CallOption) (*CheckPolicyRsp, error) {
+	out := new(CheckPolicyRsp)
+	err := c.cc.Invoke(ctx, LakeAdmin_CheckPolicy_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LakeAdminServer is the server API for LakeAdmin service.
+// All implementations must embed UnimplementedLakeAdminServer
+// for forward compatibility
+type LakeAdminServer interface {
+	CheckPolicy(context.Context, *CheckPolicyReq) (*CheckPolicyRsp, error)
+	mustEmbedUnimplementedLakeAdminServer()
+}
+
+// UnimplementedLakeAdminServer must be embedded to have forward compatible implementations.
+type UnimplementedLakeAdminServer struct {
+}
+
+func (UnimplementedLakeAdminServer) CheckPolicy(context.Context, *CheckPolicyReq) (*CheckPolicyRsp, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CheckPolicy not implemented")
+}
+func (UnimplementedLakeAdminServer) mustEmbedUnimplementedLakeAdminServer() {}
+
+// UnsafeLakeAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LakeAdminServer will
+// result in compilation errors.
+type UnsafeLakeAdminServer interface {
+	mustEmbedUnimplementedLakeAdminServer()
+}
+
+func RegisterLakeAdminServer(s grpc.ServiceRegistrar, srv LakeAdminServer) {
+	s.RegisterService(&LakeAdmin_ServiceDesc, srv)
+}
+
+func _LakeAdmin_CheckPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckPolicyReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LakeAdminServer).CheckPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LakeAdmin_CheckPolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LakeAdminServer).CheckPolicy(ctx, req.(*CheckPolicyReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// LakeAdmin_ServiceDesc is the grpc.ServiceDesc for LakeAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LakeAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "lake.LakeAdmin",
+	HandlerType: (*LakeAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CheckPolicy",
+			Handler:    _LakeAdmin_CheckPolicy_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "lake/service.proto",
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/tyeryan/l-common-util/config"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AlgorithmTokenBucket   = "token_bucket"
+	AlgorithmSlidingWindow = "sliding_window"
+)
+
+type RateLimitConfig struct {
+	Enable bool `configdefault:"true" configstruct:"RATE_LIMIT_CONFIG_ENABLE"`
+	// Algorithm is token_bucket or sliding_window
+	Algorithm                string `configdefault:"token_bucket" configstruct:"RATE_LIMIT_CONFIG_ALGORITHM"`
+	PublicRequests           int32  `configdefault:"60" configstruct:"RATE_LIMIT_CONFIG_PUBLIC_REQUESTS" configvalidate:"min=0"`
+	PublicWindowInSec        int32  `configdefault:"60" configstruct:"RATE_LIMIT_CONFIG_PUBLIC_WINDOW_IN_SEC" configvalidate:"duration=0s.."`
+	AuthenticatedRequests    int32  `configdefault:"600" configstruct:"RATE_LIMIT_CONFIG_AUTHENTICATED_REQUESTS" configvalidate:"min=0"`
+	AuthenticatedWindowInSec int32  `configdefault:"60" configstruct:"RATE_LIMIT_CONFIG_AUTHENTICATED_WINDOW_IN_SEC" configvalidate:"duration=0s.."`
+	// PrincipalRequests overrides the requests per window of single principals,
+	// entries look like "user:<id>=1000" or "apikey:<id>=5000"
+	PrincipalRequests []string `configstruct:"RATE_LIMIT_CONFIG_PRINCIPAL_REQUESTS"`
+	// RedisRetryInSec how long the local fallback is used after redis failed before redis is tried again
+	RedisRetryInSec int32 `configdefault:"5" configstruct:"RATE_LIMIT_CONFIG_REDIS_RETRY_IN_SEC" configvalidate:"duration=0s.."`
+}
+
+// Limit allows Requests per Window, Name keeps the counters of route groups apart
+type Limit struct {
+	Name     string
+	Requests int64
+	Window   time.Duration
+}
+
+func ProvideRateLimitConfig(ctx context.Context, configStore config.ConfigStore) (*RateLimitConfig, error) {
+	cnf := RateLimitConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
+}
+
+// Public and Authenticated pick the limit of a route group for Limiter.Filter
+var (
+	Public        = (*RateLimitConfig).Public
+	Authenticated = (*RateLimitConfig).Authenticated
+)
+
+// Validate checks what the types don't, a reload with an invalid config is rejected
+func (cnf *RateLimitConfig) Validate() error {
+	if cnf.Algorithm != AlgorithmTokenBucket && cnf.Algorithm != AlgorithmSlidingWindow {
+		return fmt.Errorf("unknown rate limit algorithm %q", cnf.Algorithm)
+	}
+	_, err := cnf.principalRequests()
+	return err
+}
+
+// Public is the limit of the routes which don't require authentication, applied per client ip
+func (cnf *RateLimitConfig) Public() Limit {
+	return Limit{Name: "public", Requests: int64(cnf.PublicRequests), Window: time.Duration(cnf.PublicWindowInSec) * time.Second}
+}
+
+// Authenticated is the limit of the routes behind AuthFilter, applied per api key or user
+func (cnf *RateLimitConfig) Authenticated() Limit {
+	return Limit{Name: "authenticated", Requests: int64(cnf.AuthenticatedRequests), Window: time.Duration(cnf.AuthenticatedWindowInSec) * time.Second}
+}
+
+// principalRequests parses PrincipalRequests into a map keyed by "user:<id>" or "apikey:<id>"
+func (cnf *RateLimitConfig) principalRequests() (map[string]int64, error) {
+	overrides := make(map[string]int64, len(cnf.PrincipalRequests))
+	for _, entry := range cnf.PrincipalRequests {
+		subject, value, ok := strings.Cut(entry, "=")
+		if !ok || (!strings.HasPrefix(subject, subjectUser) && !strings.HasPrefix(subject, subjectAPIKey)) {
+			return nil, fmt.Errorf("invalid principal rate limit %q", entry)
+		}
+		requests, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || requests <= 0 {
+			return nil, fmt.Errorf("invalid principal rate limit %q", entry)
+		}
+		overrides[strings.TrimSpace(subject)] = requests
+	}
+	return overrides, nil
+}
@@ -0,0 +1,35 @@
+package ratelimit
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strconv"
+)
+
+// UnaryInterceptor applies the limits to grpc calls like Filter does to the routes: the public
+// limit per client ip to the listed public methods, the authenticated limit per api key or user
+// to the calls of a verified caller. It runs after the interceptor which verifies the credentials,
+// other calls without one, like health checks, pass through.
+func (l *Limiter) UnaryInterceptor(publicMethods ...string) grpc.UnaryServerInterceptor {
+	public := make(map[string]bool, len(publicMethods))
+	for _, fullMethod := range publicMethods {
+		public[fullMethod] = true
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var result *Result
+		if public[info.FullMethod] {
+			ip, _ := l.clientIP.FromContext(ctx)
+			result, _ = l.check(ctx, Public, subjectIP+ip)
+		} else if subject, ok := principalSubject(ctx); ok {
+			result, _ = l.check(ctx, Authenticated, subject)
+		}
+		if result != nil && !result.Allowed {
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.FormatInt(ceilSeconds(result.RetryAfter), 10)))
+			return nil, status.Error(codes.ResourceExhausted, "too many requests")
+		}
+		return handler(ctx, req)
+	}
+}
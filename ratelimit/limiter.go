@@ -0,0 +1,198 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/wire"
+	"github.com/tyeryan/l-common-util/cache"
+	ctxutil "github.com/tyeryan/l-protocol/context"
+	"lake-go/clientip"
+	"lake-go/filter"
+	"lake-go/responder"
+	"lake-go/tenant"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvideRateLimitConfig,
+		ProvideLimiter,
+	)
+)
+
+const (
+	keyPrefix = "lake-go:ratelimit:"
+
+	subjectUser   = "user:"
+	subjectAPIKey = "apikey:"
+	subjectIP     = "ip:"
+)
+
+// Result of taking one request from a limit
+type Result struct {
+	Allowed    bool
+	Limit      int64
+	Remaining  int64
+	RetryAfter time.Duration
+	// Reset is the time until the full limit is available again
+	Reset time.Duration
+}
+
+type store interface {
+	take(ctx context.Context, key string, requests int64, window time.Duration, now time.Time) (*Result, error)
+}
+
+// Limiter limits requests per principal in redis and falls back to in-memory counters when
+// redis is unreachable, so an outage of redis neither blocks nor unprotects the api.
+type Limiter struct {
+	clientIP    *clientip.Resolver
+	cacheClient cache.DistributedCache
+	// settings are replaced as a whole when the config is reloaded
+	settings atomic.Pointer[settings]
+
+	mutex       sync.RWMutex
+	redisFailed time.Time
+}
+
+type settings struct {
+	cnf        *RateLimitConfig
+	overrides  map[string]int64
+	redisStore store
+	localStore store
+}
+
+func ProvideLimiter(ctx context.Context, cnf *RateLimitConfig, cacheClient cache.DistributedCache, clientIP *clientip.Resolver) (*Limiter, error) {
+	l := &Limiter{
+		clientIP:    clientIP,
+		cacheClient: cacheClient,
+	}
+	if err := l.Apply(cnf); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Apply switches to a new config, the counters are kept unless the algorithm changed
+func (l *Limiter) Apply(cnf *RateLimitConfig) error {
+	if err := cnf.Validate(); err != nil {
+		return err
+	}
+	overrides, _ := cnf.principalRequests()
+	next := &settings{
+		cnf:       cnf,
+		overrides: overrides,
+	}
+	if current := l.settings.Load(); current != nil && current.cnf.Algorithm == cnf.Algorithm {
+		next.redisStore, next.localStore = current.redisStore, current.localStore
+	} else {
+		next.redisStore = &redisStore{algorithm: cnf.Algorithm, cacheClient: l.cacheClient}
+		next.localStore = newLocalStore(cnf.Algorithm)
+	}
+	l.settings.Store(next)
+	return nil
+}
+
+// Filter applies the limit picked from the current config to the route group, use it after
+// AuthFilter so requests are counted per api key or user instead of per client ip.
+func (l *Limiter) Filter(pick func(cnf *RateLimitConfig) Limit) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			result, window := l.check(r.Context(), pick, l.subject(r))
+			if result == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			setHeaders(w, result, window)
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.FormatInt(ceilSeconds(result.RetryAfter), 10))
+				responder.Error(w, r, responder.NewProblem(http.StatusTooManyRequests, responder.ErrCodeRateLimited, "too many requests"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// check counts one request of subject against the picked limit, it returns nil when the limit is disabled
+func (l *Limiter) check(ctx context.Context, pick func(cnf *RateLimitConfig) Limit, subject string) (*Result, time.Duration) {
+	s := l.settings.Load()
+	limit := pick(s.cnf)
+	if !s.cnf.Enable || limit.Requests <= 0 || limit.Window <= 0 {
+		return nil, 0
+	}
+	requests := limit.Requests
+	if override, ok := s.overrides[subject]; ok {
+		requests = override
+	}
+
+	result := l.Take(ctx, keyPrefix+limit.Name+":"+subject, requests, limit.Window)
+	if !result.Allowed {
+		tenant.GetLogger(ctx, "ratelimit.check").Warnw(ctx, "rate limit exceeded", "limit", limit.Name, "subject", subject)
+	}
+	return result, limit.Window
+}
+
+// Take counts one request against key, redis is skipped for RedisRetryInSec after it failed
+func (l *Limiter) Take(ctx context.Context, key string, requests int64, window time.Duration) *Result {
+	now := time.Now()
+	s := l.settings.Load()
+
+	l.mutex.RLock()
+	useRedis := now.Sub(l.redisFailed) >= time.Duration(s.cnf.RedisRetryInSec)*time.Second
+	l.mutex.RUnlock()
+
+	if useRedis {
+		result, err := s.redisStore.take(ctx, key, requests, window, now)
+		if err == nil {
+			return result
+		}
+		tenant.GetLogger(ctx, "ratelimit.Take").Warne(ctx, "rate limit in redis found error, using local limits", err)
+		l.mutex.Lock()
+		l.redisFailed = now
+		l.mutex.Unlock()
+	}
+
+	result, _ := s.localStore.take(ctx, key, requests, window, now)
+	return result
+}
+
+// subject identifies the caller, requests without credentials are counted per client ip
+func (l *Limiter) subject(r *http.Request) string {
+	if subject, ok := principalSubject(r.Context()); ok {
+		return subject
+	}
+	return subjectIP + l.clientIP.FromRequest(r)
+}
+
+// principalSubject identifies the authenticated caller, the api key wins over the user as every
+// key has its own budget
+func principalSubject(ctx context.Context) (string, bool) {
+	if keyID, ok := ctxutil.Read(ctx, filter.APIKeyID); ok && keyID != "" {
+		return subjectAPIKey + keyID, true
+	}
+	if userID, ok := ctxutil.Read(ctx, ctxutil.UserID); ok && userID != "" {
+		return subjectUser + userID, true
+	}
+	return "", false
+}
+
+// setHeaders sets the RateLimit headers of draft-ietf-httpapi-ratelimit-headers
+func setHeaders(w http.ResponseWriter, result *Result, window time.Duration) {
+	remaining := result.Remaining
+	if remaining < 0 {
+		remaining = 0
+	}
+	w.Header().Set("RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+	w.Header().Set("RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+	w.Header().Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.Reset), 10))
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", result.Limit, ceilSeconds(window)))
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
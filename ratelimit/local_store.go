@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// localStore implements both algorithms in memory, it is only used while redis is unreachable
+// so the limits then apply per instance instead of globally.
+type localStore struct {
+	algorithm string
+
+	mutex     sync.Mutex
+	entries   map[string]*localEntry
+	lastSweep time.Time
+}
+
+type localEntry struct {
+	tokens    float64
+	ts        time.Time
+	hits      []time.Time
+	expiresAt time.Time
+}
+
+func newLocalStore(algorithm string) *localStore {
+	return &localStore{
+		algorithm: algorithm,
+		entries:   map[string]*localEntry{},
+	}
+}
+
+func (s *localStore) take(ctx context.Context, key string, requests int64, window time.Duration, now time.Time) (*Result, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sweep(now, window)
+
+	entry, ok := s.entries[key]
+	if !ok || now.After(entry.expiresAt) {
+		entry = &localEntry{tokens: float64(requests), ts: now}
+		s.entries[key] = entry
+	}
+	entry.expiresAt = now.Add(window)
+
+	if s.algorithm == AlgorithmSlidingWindow {
+		return entry.slidingWindow(requests, window, now), nil
+	}
+	return entry.tokenBucket(requests, window, now), nil
+}
+
+func (e *localEntry) tokenBucket(requests int64, window time.Duration, now time.Time) *Result {
+	rate := float64(requests) / float64(window)
+	if elapsed := now.Sub(e.ts); elapsed > 0 {
+		e.tokens = math.Min(float64(requests), e.tokens+float64(elapsed)*rate)
+	}
+	e.ts = now
+
+	result := &Result{Limit: requests}
+	if e.tokens >= 1 {
+		e.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil((1 - e.tokens) / rate))
+	}
+	result.Remaining = int64(e.tokens)
+	result.Reset = time.Duration(math.Ceil((float64(requests) - e.tokens) / rate))
+	return result
+}
+
+func (e *localEntry) slidingWindow(requests int64, window time.Duration, now time.Time) *Result {
+	start := now.Add(-window)
+	kept := e.hits[:0]
+	for _, hit := range e.hits {
+		if hit.After(start) {
+			kept = append(kept, hit)
+		}
+	}
+	e.hits = kept
+
+	result := &Result{Limit: requests}
+	if int64(len(e.hits)) < requests {
+		e.hits = append(e.hits, now)
+		result.Allowed = true
+	}
+	result.Remaining = requests - int64(len(e.hits))
+	if len(e.hits) > 0 {
+		result.Reset = e.hits[0].Add(window).Sub(now)
+	}
+	if !result.Allowed {
+		result.RetryAfter = result.Reset
+	}
+	return result
+}
+
+// sweep drops expired entries at most once per window
+func (s *localStore) sweep(now time.Time, window time.Duration) {
+	if now.Sub(s.lastSweep) < window {
+		return
+	}
+	s.lastSweep = now
+	for key, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
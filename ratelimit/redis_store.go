@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis"
+	"github.com/tyeryan/l-common-util/cache"
+	"lake-go/tracing"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// tokenBucketScript refills the bucket for the time passed since the last call and takes one token.
+// The caller passes the clock so all instances and the local fallback agree on it.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local rate = capacity / window
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = capacity
+  ts = now
+end
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], window)
+return {allowed, math.floor(tokens), retry, math.ceil((capacity - tokens) / rate)}
+`)
+
+// slidingWindowScript keeps the timestamps of the accepted requests of the window in a sorted set
+var slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+  redis.call('ZADD', KEYS[1], now, ARGV[4])
+  count = count + 1
+  allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], window)
+
+local reset = 0
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if oldest[2] then
+  reset = tonumber(oldest[2]) + window - now
+end
+local retry = 0
+if allowed == 0 then
+  retry = reset
+end
+return {allowed, limit - count, retry, reset}
+`)
+
+// redisStore shares the counters between all instances, every call is a single atomic script run
+type redisStore struct {
+	algorithm   string
+	cacheClient cache.DistributedCache
+}
+
+func (s *redisStore) take(ctx context.Context, key string, requests int64, window time.Duration, now time.Time) (*Result, error) {
+	windowMs := window.Milliseconds()
+	nowMs := now.UnixMilli()
+
+	var cmd *redis.Cmd
+	client := tracing.Redis(ctx, s.cacheClient.GetClient())
+	if s.algorithm == AlgorithmSlidingWindow {
+		member := strconv.FormatInt(nowMs, 10) + "-" + strconv.FormatInt(rand.Int63(), 36)
+		cmd = slidingWindowScript.Run(client, []string{key}, requests, windowMs, nowMs, member)
+	} else {
+		cmd = tokenBucketScript.Run(client, []string{key}, requests, windowMs, nowMs)
+	}
+
+	values, err := cmd.Result()
+	if err != nil {
+		return nil, err
+	}
+	fields, ok := values.([]interface{})
+	if !ok || len(fields) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+	ints := make([]int64, len(fields))
+	for i, field := range fields {
+		if ints[i], ok = field.(int64); !ok {
+			return nil, fmt.Errorf("unexpected rate limit script result %v", values)
+		}
+	}
+	return &Result{
+		Allowed:    ints[0] == 1,
+		Limit:      requests,
+		Remaining:  ints[1],
+		RetryAfter: time.Duration(ints[2]) * time.Millisecond,
+		Reset:      time.Duration(ints[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	logutil "github.com/tyeryan/l-protocol/log"
+	"lake-go/config"
+	"lake-go/filter"
+	"lake-go/logging"
+	"lake-go/policy"
+	"lake-go/ratelimit"
+)
+
+// subscribeConfig hands reloaded configs to the components which can change without a restart.
+// Everything else keeps the config it started with.
+func subscribeConfig(ctx context.Context, svc *service) error {
+	log := logutil.GetLogger("lake-go")
+	watcher := svc.configWatcher
+
+	if _, err := config.Subscribe(watcher, "cors", func(cnf *filter.CORSConfig) {
+		if err := svc.cors.Apply(cnf); err != nil {
+			log.Errore(ctx, "apply cors config found error", err)
+		}
+	}); err != nil {
+		return err
+	}
+	if _, err := config.Subscribe(watcher, "rateLimit", func(cnf *ratelimit.RateLimitConfig) {
+		if err := svc.rateLimiter.Apply(cnf); err != nil {
+			log.Errore(ctx, "apply rate limit config found error", err)
+		}
+	}); err != nil {
+		return err
+	}
+	if _, err := config.Subscribe(watcher, "lAuth", svc.lauthTimeout.Apply); err != nil {
+		return err
+	}
+	// edits of the policy file apply as well, an invalid policy rejects the reload
+	if _, err := config.SubscribeFile(watcher, "policy", func(cnf *policy.PolicyConfig) string { return cnf.File },
+		policy.Load, svc.policyEngine.Apply); err != nil {
+		return err
+	}
+
+	// Install only saw the environment, the level of the config file applies from here on
+	logConfig, err := config.Subscribe(watcher, "log", func(cnf *logging.LogConfig) {
+		if err := logging.SetLevel("", cnf.Level); err != nil {
+			log.Errore(ctx, "apply log level found error", err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return logging.SetLevel("", logConfig.Level)
+}
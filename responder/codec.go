@@ -0,0 +1,120 @@
+package responder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/vmihailenco/msgpack"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"mime"
+	"net/http"
+)
+
+const (
+	MediaTypeJSON     = "application/json"
+	MediaTypeProtobuf = "application/x-protobuf"
+	MediaTypeMsgpack  = "application/msgpack"
+	MediaTypeNDJSON   = "application/x-ndjson"
+
+	ErrCodeNotAcceptable        = "NOT_ACCEPTABLE"
+	ErrCodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	ErrCodeRequestTooLarge      = "REQUEST_TOO_LARGE"
+
+	maxBodyBytes = 1 << 20
+)
+
+var (
+	errNotProto = errors.New("value is not a proto message")
+
+	protoJSONUnmarshal = protojson.UnmarshalOptions{
+		DiscardUnknown: true,
+	}
+)
+
+// encode marshals v in the media type, protobuf only accepts proto messages
+func encode(mediaType string, v interface{}) ([]byte, error) {
+	switch mediaType {
+	case MediaTypeProtobuf:
+		pb, ok := v.(proto.Message)
+		if !ok {
+			return nil, errNotProto
+		}
+		return proto.Marshal(pb)
+	case MediaTypeMsgpack:
+		var buf bytes.Buffer
+		// json tags keep the field names the same as in the json bodies
+		if err := msgpack.NewEncoder(&buf).UseJSONTag(true).Encode(v); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case MediaTypeNDJSON:
+		buf, err := marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return append(buf, '\n'), nil
+	default:
+		return marshal(v)
+	}
+}
+
+// canEncode tells whether v can be written in the media type
+func canEncode(mediaType string, v interface{}) bool {
+	if mediaType == MediaTypeProtobuf {
+		_, ok := v.(proto.Message)
+		return ok
+	}
+	return true
+}
+
+// Decode reads the request body into v according to its Content-Type, a body without
+// Content-Type is read as json. Unsupported media types return a 415 problem, bodies over
+// 1 MiB a 413 problem and malformed bodies a 400 problem.
+func Decode(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	mediaType := MediaTypeJSON
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		parsed, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return unsupportedMediaType(contentType)
+		}
+		mediaType = parsed
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return NewProblem(http.StatusRequestEntityTooLarge, ErrCodeRequestTooLarge, "request body exceeds 1 MiB")
+	}
+	if err != nil {
+		return InvalidRequest("unable to read request body")
+	}
+
+	pb, isProto := v.(proto.Message)
+	switch mediaType {
+	case MediaTypeJSON:
+		if isProto {
+			err = protoJSONUnmarshal.Unmarshal(body, pb)
+		} else {
+			err = json.Unmarshal(body, v)
+		}
+	case MediaTypeProtobuf:
+		if !isProto {
+			return unsupportedMediaType(mediaType)
+		}
+		err = proto.Unmarshal(body, pb)
+	case MediaTypeMsgpack:
+		err = msgpack.NewDecoder(bytes.NewReader(body)).UseJSONTag(true).Decode(v)
+	default:
+		return unsupportedMediaType(mediaType)
+	}
+	if err != nil {
+		return InvalidRequest("request body is not valid " + mediaType)
+	}
+	return nil
+}
+
+func unsupportedMediaType(mediaType string) *Problem {
+	return NewProblem(http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, "content type "+mediaType+" is not supported")
+}
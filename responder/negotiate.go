@@ -0,0 +1,152 @@
+package responder
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type negotiationKey struct{}
+
+// negotiation is what Negotiate resolved for the request
+type negotiation struct {
+	// acceptable the allowed media types the client accepts, most preferred first
+	acceptable []string
+}
+
+type mediaRange struct {
+	mediaType string
+	q         float64
+	index     int
+}
+
+// Negotiate is the per route allowlist of media types. It answers 406 when the client accepts
+// none of them and 415 when the request body is in a type outside of the list, the first type
+// is used when the client has no preference. Routes without it only speak json.
+func Negotiate(allowed ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+
+			if hasBody(r) {
+				mediaType := MediaTypeJSON
+				if contentType := r.Header.Get("Content-Type"); contentType != "" {
+					mediaType, _, _ = mime.ParseMediaType(contentType)
+				}
+				if mediaType == MediaTypeNDJSON || !slices.Contains(allowed, mediaType) {
+					Error(w, r, unsupportedMediaType(mediaType))
+					return
+				}
+			}
+
+			acceptable := acceptableTypes(r.Header.Get("Accept"), allowed)
+			if len(acceptable) == 0 {
+				Error(w, r, NewProblem(http.StatusNotAcceptable, ErrCodeNotAcceptable,
+					"acceptable content types are "+strings.Join(allowed, ", ")))
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), negotiationKey{}, &negotiation{acceptable: acceptable})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Respond writes v in the most preferred media type which can represent it, routes
+// without Negotiate always get json.
+func Respond(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	for _, mediaType := range negotiated(r) {
+		if !canEncode(mediaType, v) {
+			continue
+		}
+		buf, err := encode(mediaType, v)
+		if err != nil {
+			Error(w, r, err)
+			return
+		}
+		contentType := mediaType
+		if mediaType == MediaTypeJSON {
+			contentType = ContentTypeJSON
+		}
+		write(w, status, contentType, buf)
+		return
+	}
+	Error(w, r, NewProblem(http.StatusNotAcceptable, ErrCodeNotAcceptable, "response can't be represented in an acceptable content type"))
+}
+
+// Streaming tells whether the client prefers the response as a NDJSON stream
+func Streaming(r *http.Request) bool {
+	acceptable := negotiated(r)
+	return len(acceptable) > 0 && acceptable[0] == MediaTypeNDJSON
+}
+
+func negotiated(r *http.Request) []string {
+	if n, ok := r.Context().Value(negotiationKey{}).(*negotiation); ok {
+		return n.acceptable
+	}
+	return []string{MediaTypeJSON}
+}
+
+// acceptableTypes orders the allowed types by the quality the Accept header gives them
+func acceptableTypes(accept string, allowed []string) []string {
+	if strings.TrimSpace(accept) == "" {
+		return allowed
+	}
+
+	var ranges []mediaRange
+	for i, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q, index: i})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		// specific types before wildcards of the same quality
+		return strings.Count(ranges[i].mediaType, "*") < strings.Count(ranges[j].mediaType, "*")
+	})
+
+	var acceptable []string
+	rejected := map[string]bool{}
+	for _, mr := range ranges {
+		for _, mediaType := range allowed {
+			if !matches(mr.mediaType, mediaType) || slices.Contains(acceptable, mediaType) || rejected[mediaType] {
+				continue
+			}
+			if mr.q <= 0 {
+				rejected[mediaType] = true
+				continue
+			}
+			acceptable = append(acceptable, mediaType)
+		}
+	}
+	return acceptable
+}
+
+func matches(mediaRange string, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*"))
+	}
+	return false
+}
+
+func hasBody(r *http.Request) bool {
+	return r.ContentLength > 0 || len(r.TransferEncoding) > 0
+}
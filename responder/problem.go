@@ -0,0 +1,116 @@
+package responder
+
+import (
+	"encoding/json"
+	"errors"
+	ctxutil "github.com/tyeryan/l-protocol/context"
+	logutil "github.com/tyeryan/l-protocol/log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+)
+
+const (
+	ErrCodeInvalidRequest   = "INVALID_REQUEST"
+	ErrCodeUnauthorized     = "UNAUTHORIZED"
+	ErrCodeForbidden        = "FORBIDDEN"
+	ErrCodeNotFound         = "NOT_FOUND"
+	ErrCodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	ErrCodeRateLimited      = "RATE_LIMITED"
+	ErrCodeInternal         = "INTERNAL_ERROR"
+	ErrCodeUnavailable      = "SERVICE_UNAVAILABLE"
+
+	problemTypePrefix = "urn:lake-go:problem:"
+)
+
+// Violation is a problem with one field of the request
+type Violation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem detail. Code is the stable identifier clients should
+// switch on, Detail is for humans and may change.
+type Problem struct {
+	Type       string       `json:"type"`
+	Title      string       `json:"title"`
+	Status     int          `json:"status"`
+	Detail     string       `json:"detail,omitempty"`
+	Instance   string       `json:"instance,omitempty"`
+	Code       string       `json:"code"`
+	RequestID  string       `json:"requestId,omitempty"`
+	Violations []*Violation `json:"violations,omitempty"`
+}
+
+// NewProblem creates a problem, the type is derived from the code
+func NewProblem(status int, code string, detail string) *Problem {
+	return &Problem{
+		Type:   problemTypePrefix + strings.ReplaceAll(strings.ToLower(code), "_", "-"),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// InvalidRequest is a 400 problem listing the field violations
+func InvalidRequest(detail string, violations ...*Violation) *Problem {
+	p := NewProblem(http.StatusBadRequest, ErrCodeInvalidRequest, detail)
+	p.Violations = violations
+	return p
+}
+
+func (p *Problem) Error() string {
+	return p.Code + ": " + p.Detail
+}
+
+// Error writes err as application/problem+json. Errors which aren't a *Problem are logged
+// and answered with a generic internal error, their message is never exposed.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+
+	var problem *Problem
+	if !errors.As(err, &problem) {
+		logutil.GetLogger("responder").Errore(ctx, "unexpected error in handler", err, "path", r.URL.Path)
+		problem = NewProblem(http.StatusInternalServerError, ErrCodeInternal, "internal error")
+	}
+
+	// copy so shared problem values aren't modified across requests
+	rsp := *problem
+	rsp.Instance = r.URL.Path
+	rsp.RequestID, _ = ctxutil.Read(ctx, ctxutil.Stan)
+
+	buf, err := json.Marshal(&rsp)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	write(w, rsp.Status, ContentTypeProblem, buf)
+}
+
+// NotFound answers unknown routes with a problem instead of the plain text default of chi
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	Error(w, r, NewProblem(http.StatusNotFound, ErrCodeNotFound, "no route for "+r.URL.Path))
+}
+
+// MethodNotAllowed answers known routes called with an unsupported method
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	Error(w, r, NewProblem(http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path))
+}
+
+// Recoverer turns panics of handlers into an internal error problem
+func Recoverer(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rvr := recover(); rvr != nil {
+				if rvr == http.ErrAbortHandler {
+					panic(rvr)
+				}
+				logutil.GetLogger("responder").Errorw(r.Context(), "handler panicked", "panic", rvr, "stack", string(debug.Stack()))
+				Error(w, r, NewProblem(http.StatusInternalServerError, ErrCodeInternal, "internal error"))
+			}
+		}()
+		next.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
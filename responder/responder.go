@@ -0,0 +1,36 @@
+package responder
+
+import (
+	"encoding/json"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"net/http"
+)
+
+const (
+	ContentTypeJSON    = "application/json; charset=utf-8"
+	ContentTypeProblem = "application/problem+json; charset=utf-8"
+)
+
+var protoJSON = protojson.MarshalOptions{
+	UseEnumNumbers:  false,
+	EmitUnpopulated: false,
+}
+
+// NoContent answers 204 without a body
+func NoContent(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	if pb, ok := v.(proto.Message); ok {
+		return protoJSON.Marshal(pb)
+	}
+	return json.Marshal(v)
+}
+
+func write(w http.ResponseWriter, status int, contentType string, buf []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, _ = w.Write(buf)
+}
@@ -0,0 +1,34 @@
+package responder
+
+import (
+	"net/http"
+)
+
+// Stream writes one json document per line (NDJSON) and flushes after each of them,
+// so clients can process long listings while they are produced.
+type Stream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func NewStream(w http.ResponseWriter, r *http.Request, status int) *Stream {
+	w.Header().Set("Content-Type", MediaTypeNDJSON)
+	w.WriteHeader(status)
+	flusher, _ := w.(http.Flusher)
+	return &Stream{w: w, flusher: flusher}
+}
+
+// Send writes v as the next line, the status is already sent so errors can only end the stream
+func (s *Stream) Send(v interface{}) error {
+	buf, err := encode(MediaTypeNDJSON, v)
+	if err != nil {
+		return err
+	}
+	if _, err := s.w.Write(buf); err != nil {
+		return err
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package router
+
+import (
+	"context"
+	"crypto/subtle"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"lake-go/filter"
+	"lake-go/handler/admin"
+	"lake-go/metrics"
+	"lake-go/responder"
+	"lake-go/server"
+	"net"
+	"net/http"
+	"net/http/pprof"
+)
+
+// AdminRoutes the handler of the admin listener, kept apart from the api routes so it is
+// never reachable through the ingress
+type AdminRoutes struct {
+	http.Handler
+}
+
+func ProvideAdminRoutes(ctx context.Context, registry *prometheus.Registry, serverConfig *server.ServerConfig, adminHandler *admin.AdminHandler) (*AdminRoutes, error) {
+	r := chi.NewRouter()
+	r.Use(filter.RequestID())
+	r.Use(responder.Recoverer)
+	r.NotFound(responder.NotFound)
+	r.MethodNotAllowed(responder.MethodNotAllowed)
+
+	// prometheus scrapes the pod ip, metrics are the only route open to it
+	r.Handle("/metrics", metrics.Handler(registry))
+
+	r.Group(func(r chi.Router) {
+		r.Use(adminGuard(serverConfig.AdminToken))
+		r.HandleFunc("/debug/pprof/", pprof.Index)
+		r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		r.HandleFunc("/debug/pprof/{profile}", pprof.Index)
+		r.Get("/admin/goroutines", adminHandler.Goroutines)
+		r.Get("/admin/buildinfo", adminHandler.BuildInfo)
+		r.Get("/admin/loglevel", adminHandler.LogLevels)
+		r.Put("/admin/loglevel", adminHandler.SetLogLevel)
+	})
+	return &AdminRoutes{Handler: r}, nil
+}
+
+// adminGuard requires the admin token as bearer token, without a configured token only
+// loopback clients are let through, e.g. through kubectl port-forward
+func adminGuard(token string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if token != "" {
+				given, ok := filter.BearerToken(r)
+				if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="lake-go-admin"`)
+					responder.Error(w, r, responder.NewProblem(http.StatusUnauthorized, responder.ErrCodeUnauthorized, "admin token required"))
+					return
+				}
+			} else if !isLoopback(r.RemoteAddr) {
+				responder.Error(w, r, responder.NewProblem(http.StatusForbidden, responder.ErrCodeForbidden, "admin routes are only served to localhost"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+func isLoopback(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -1,16 +1,35 @@
 package router
 
 import (
-	"github.com/go-chi/render"
-	logutil "github.com/tyeryan/l-protocol/log"
+	"lake-go/health"
+	"lake-go/responder"
+	"lake-go/tenant"
 	"net/http"
 )
 
 func (h *LakeHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	log := logutil.GetLogger("HealthCheck")
 	ctx := r.Context()
+	log := tenant.GetLogger(ctx, "HealthCheck")
 	log.Infow(ctx, "Health check called")
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	render.Status(r, http.StatusOK)
-	render.JSON(w, r, map[string]interface{}{"health": true})
+	responder.Respond(w, r, http.StatusOK, map[string]interface{}{"health": true})
+}
+
+// Livez only tells the process is serving, dependencies are left to Readyz so an outage
+// of one of them doesn't get every pod restarted.
+func (h *LakeHandler) Livez(w http.ResponseWriter, r *http.Request) {
+	responder.Respond(w, r, http.StatusOK, map[string]interface{}{"status": health.StatusOK})
+}
+
+// Readyz reports every dependency, it fails when one of them is down or the service is draining
+func (h *LakeHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := tenant.GetLogger(ctx, "Readyz")
+
+	report := h.healthRegistry.Readiness(ctx)
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		log.Warnw(ctx, "service not ready", "report", report)
+		status = http.StatusServiceUnavailable
+	}
+	responder.Respond(w, r, status, report)
 }
@@ -1,8 +1,13 @@
 package router
 
+import "lake-go/health"
+
 type LakeHandler struct {
+	healthRegistry *health.Registry
 }
 
-func ProvideLakeHandler() *LakeHandler {
-	return &LakeHandler{}
+func ProvideLakeHandler(healthRegistry *health.Registry) *LakeHandler {
+	return &LakeHandler{
+		healthRegistry: healthRegistry,
+	}
 }
@@ -0,0 +1,118 @@
+package router
+
+import (
+	"github.com/tyeryan/l-protocol/go/lauth"
+	"lake-go/apikey"
+	"lake-go/audit"
+	"lake-go/handler/admin"
+	"lake-go/handler/auth"
+	"lake-go/health"
+	"lake-go/openapi"
+	"lake-go/responder"
+	"lake-go/session"
+	"net/http"
+)
+
+// per route allowlists of the media types of request and response bodies
+var (
+	structuredMediaTypes = []string{responder.MediaTypeJSON, responder.MediaTypeMsgpack}
+	listingMediaTypes    = []string{responder.MediaTypeJSON, responder.MediaTypeMsgpack, responder.MediaTypeNDJSON}
+	authMediaTypes       = []string{responder.MediaTypeJSON, responder.MediaTypeProtobuf, responder.MediaTypeMsgpack}
+)
+
+// operations documents every route of ProvideRoutes, ProvideRoutes fails when a route is
+// added without an entry here
+func operations() []*openapi.Operation {
+	healthSchema := &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{"health": {Type: "boolean"}}}
+	statusSchema := &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{"status": {Type: "string"}}}
+
+	return []*openapi.Operation{
+		{
+			Method: http.MethodGet, Route: "/v1/healthcheck", Summary: "Legacy health check",
+			Tags: []string{"health"}, Public: true,
+			Responses: map[int]interface{}{http.StatusOK: healthSchema},
+		},
+		{
+			Method: http.MethodGet, Route: "/v1/livez", Summary: "Liveness of the process",
+			Tags: []string{"health"}, Public: true,
+			Responses: map[int]interface{}{http.StatusOK: statusSchema},
+		},
+		{
+			Method: http.MethodGet, Route: "/v1/readyz", Summary: "Readiness of the service and its dependencies",
+			Tags: []string{"health"}, Public: true,
+			Responses: map[int]interface{}{http.StatusOK: &health.Report{}, http.StatusServiceUnavailable: &health.Report{}},
+		},
+		{
+			Method: http.MethodGet, Route: "/v1/openapi.json", Summary: "This document",
+			Tags: []string{"docs"}, Public: true,
+			Responses: map[int]interface{}{http.StatusOK: &openapi.Schema{Type: "object"}},
+		},
+		{Method: http.MethodGet, Route: "/v1/docs", Public: true, Hidden: true},
+		{Method: http.MethodGet, Route: "/v1/docs/*", Public: true, Hidden: true},
+		{
+			Method: http.MethodPost, Route: "/v1/auth/login", Summary: "Log in with username and password",
+			Tags: []string{"auth"}, Public: true, MediaTypes: authMediaTypes,
+			Request:   &lauth.AuthReq{},
+			Responses: map[int]interface{}{http.StatusOK: &lauth.AuthRsp{}},
+		},
+		{
+			Method: http.MethodPost, Route: "/v1/auth/logout", Summary: "Revoke the bearer token of the request",
+			Tags:      []string{"auth"},
+			Responses: map[int]interface{}{http.StatusNoContent: nil},
+		},
+		{
+			Method: http.MethodGet, Route: "/v1/auth/sessions", Summary: "List the sessions of the current user",
+			Tags: []string{"auth"}, MediaTypes: listingMediaTypes,
+			Responses:  map[int]interface{}{http.StatusOK: &auth.ListSessionsRsp{}},
+			StreamItem: &auth.SessionRsp{Session: &session.Session{}},
+		},
+		{
+			Method: http.MethodDelete, Route: "/v1/auth/sessions/{id}", Summary: "Revoke a session of the current user",
+			Tags:      []string{"auth"},
+			Responses: map[int]interface{}{http.StatusNoContent: nil},
+		},
+		{
+			Method: http.MethodPost, Route: "/v1/auth/apikeys", Summary: "Create an api key for the current user",
+			Tags: []string{"auth"}, MediaTypes: structuredMediaTypes,
+			Request:   &auth.CreateAPIKeyReqBody{},
+			Responses: map[int]interface{}{http.StatusCreated: &auth.CreateAPIKeyRsp{}},
+		},
+		{
+			Method: http.MethodGet, Route: "/v1/auth/apikeys", Summary: "List the api keys of the current user",
+			Tags: []string{"auth"}, MediaTypes: listingMediaTypes,
+			Responses:  map[int]interface{}{http.StatusOK: &auth.ListAPIKeysRsp{}},
+			StreamItem: &apikey.APIKey{},
+		},
+		{
+			Method: http.MethodDelete, Route: "/v1/auth/apikeys/{id}", Summary: "Revoke an api key of the current user",
+			Tags:      []string{"auth"},
+			Responses: map[int]interface{}{http.StatusNoContent: nil},
+		},
+		{
+			Method: http.MethodGet, Route: "/v1/admin/policy/check", Summary: "Evaluate the authorization policy",
+			Tags: []string{"admin"}, MediaTypes: structuredMediaTypes,
+			Query: []*openapi.Parameter{
+				{Name: "roles", Description: "comma separated roles"},
+				{Name: "method", Description: "http method", Required: true},
+				{Name: "route", Description: "chi route pattern", Required: true},
+			},
+			Responses: map[int]interface{}{http.StatusOK: &admin.PolicyCheckRsp{}},
+		},
+		{
+			Method: http.MethodGet, Route: "/v1/audit", Summary: "Query the audit trail of the tenant, newest first, requires the admin role",
+			Tags: []string{"admin"}, MediaTypes: listingMediaTypes,
+			Query: []*openapi.Parameter{
+				{Name: "actor", Description: "user id, the username for logins"},
+				{Name: "action", Description: "auth.login, a method and route like GET /v1/auth/sessions or a grpc method"},
+				{Name: "resource", Description: "path of the resource, a trailing * matches a prefix"},
+				{Name: "outcome", Description: "success, denied or failure"},
+				{Name: "from", Description: "RFC 3339 time, inclusive"},
+				{Name: "to", Description: "RFC 3339 time, exclusive"},
+				{Name: "limit", Description: "page size, 50 by default"},
+				{Name: "cursor", Description: "nextCursor of the previous page"},
+			},
+			Responses:  map[int]interface{}{http.StatusOK: &admin.ListAuditEventsRsp{}},
+			StreamItem: &audit.Event{},
+		},
+	}
+}
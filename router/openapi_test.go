@@ -0,0 +1,46 @@
+package router
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5"
+	"lake-go/audit"
+	"lake-go/filter"
+	"lake-go/handler/admin"
+	"lake-go/handler/auth"
+	"lake-go/metrics"
+	"lake-go/openapi"
+	"lake-go/ratelimit"
+	"lake-go/server"
+	"lake-go/tenant"
+	"lake-go/tracing"
+	"testing"
+)
+
+// TestOperationsCoverRoutes fails when a route is added without its operation or an operation
+// outlives its route, ProvideRoutes only logs it. The filters and handlers are never invoked,
+// building the route tree doesn't need their dependencies.
+func TestOperationsCoverRoutes(t *testing.T) {
+	handler, err := ProvideRoutes(context.Background(),
+		&filter.AuthFilter{},
+		&filter.PolicyFilter{},
+		&LakeHandler{},
+		&auth.AuthHandler{},
+		&admin.AdminHandler{},
+		&filter.AccessLogFilter{},
+		&filter.RequestLogger{},
+		&filter.CORS{},
+		&tenant.Resolver{},
+		&server.ServerConfig{},
+		&ratelimit.Limiter{},
+		&metrics.HTTPMetrics{},
+		&tracing.Tracing{},
+		&audit.Recorder{},
+	)
+	if err != nil {
+		t.Fatalf("ProvideRoutes: %v", err)
+	}
+
+	if _, err := openapi.Build(&openapi.Info{Title: "lake-go", Version: "v1"}, handler.(chi.Routes), operations()); err != nil {
+		t.Fatal(err)
+	}
+}
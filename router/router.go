@@ -1,63 +1,142 @@
 package router
 
 import (
+	"context"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/cors"
-	"github.com/go-chi/render"
 	"github.com/google/wire"
-	"github.com/tyeryan/l-common-util/apm"
+	logutil "github.com/tyeryan/l-protocol/log"
+	"lake-go/apikey"
+	"lake-go/audit"
+	"lake-go/clientip"
+	"lake-go/db"
 	"lake-go/filter"
 	"lake-go/grpcclient"
+	"lake-go/handler/admin"
 	"lake-go/handler/auth"
+	"lake-go/health"
+	"lake-go/loginguard"
+	"lake-go/metrics"
+	"lake-go/openapi"
+	"lake-go/policy"
+	"lake-go/ratelimit"
+	"lake-go/responder"
+	"lake-go/server"
+	"lake-go/session"
+	"lake-go/tenant"
+	"lake-go/tracing"
 	"net/http"
-	"time"
 )
 
 var (
 	WireSet = wire.NewSet(
 		ProvideRoutes,
 		ProvideLakeHandler,
+		ProvideAdminRoutes,
 		grpcclient.ProvideLAuthClient,
+		grpcclient.ProvideLAuthConn,
 		grpcclient.ProvideLAuthConfig,
+		grpcclient.ProvideLAuthTimeout,
 		auth.ProvideAuthHandler,
+		session.WireSet,
+		policy.WireSet,
+		db.WireSet,
+		apikey.WireSet,
+		audit.WireSet,
+		loginguard.WireSet,
+		clientip.WireSet,
+		tenant.WireSet,
+		health.WireSet,
+		metrics.WireSet,
+		tracing.WireSet,
+		server.WireSet,
+		ratelimit.WireSet,
+		admin.ProvideAdminHandler,
 	)
 )
 
 type UserIDContextKey string
 
-func ProvideRoutes(
+func ProvideRoutes(ctx context.Context,
 	authFilter *filter.AuthFilter,
+	policyFilter *filter.PolicyFilter,
 	lakeHandler *LakeHandler,
 	authHandler *auth.AuthHandler,
-	apmConfig *apm.ApmConfig,
+	adminHandler *admin.AdminHandler,
 	accessLogFilter *filter.AccessLogFilter,
-) http.Handler {
+	requestLogger *filter.RequestLogger,
+	corsFilter *filter.CORS,
+	tenantResolver *tenant.Resolver,
+	serverConfig *server.ServerConfig,
+	rateLimiter *ratelimit.Limiter,
+	httpMetrics *metrics.HTTPMetrics,
+	tracer *tracing.Tracing,
+	auditRecorder *audit.Recorder,
+) (http.Handler, error) {
 	r := chi.NewRouter()
 
-	//r.Use(middleware.RealIP)
-	//if apmConfig.Enable {
-	//	r.Use(apmc)
-	//}
-
-	r.Use(filter.RequestResponseLogger())
-	r.Use(render.SetContentType(render.ContentTypeJSON))
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
-
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Goog-AuthUser", "X-Request-Id"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300, // Maximum value not ignored by any of major browsers
-	}))
+	r.Use(filter.RequestID())
+	r.Use(tracer.Filter())
+	r.Use(httpMetrics.Filter())
+	r.Use(tenantResolver.Filter())
+	r.Use(requestLogger.Filter())
+	r.Use(responder.Recoverer)
+	r.Use(middleware.Timeout(serverConfig.RequestTimeout()))
+
+	r.Use(corsFilter.Filter())
+
+	r.NotFound(responder.NotFound)
+	r.MethodNotAllowed(responder.MethodNotAllowed)
+
+	structured := responder.Negotiate(structuredMediaTypes...)
+	listing := responder.Negotiate(listingMediaTypes...)
+	authMessages := responder.Negotiate(authMediaTypes...)
+
+	// the document is built from the finished route tree below
+	var spec http.Handler
 
 	r.Route("/v1", func(r chi.Router) {
-		r.Use(accessLogFilter.Filter())
-		r.Get("/healthcheck", lakeHandler.HealthCheck)
+		r.Group(func(r chi.Router) {
+			r.Use(accessLogFilter.Filter())
+			r.Get("/healthcheck", lakeHandler.HealthCheck)
+			r.Get("/livez", lakeHandler.Livez)
+			r.Get("/readyz", lakeHandler.Readyz)
+			r.Get("/openapi.json", func(w http.ResponseWriter, r *http.Request) { spec.ServeHTTP(w, r) })
+			r.Get("/docs", http.RedirectHandler("/v1/docs/", http.StatusMovedPermanently).ServeHTTP)
+			r.Get("/docs/*", openapi.SwaggerUI("/v1/docs/", "lake-go api", "/v1/openapi.json").ServeHTTP)
+			// probes are left out of the public limit, kubelet probes every pod of a node from the same ip
+			r.With(rateLimiter.Filter(ratelimit.Public), authMessages).Post("/auth/login", authHandler.Authenticate)
+		})
+
+		// routes in this group require a valid bearer token
+		r.Group(func(r chi.Router) {
+			r.Use(authFilter.Filter())
+			r.Use(accessLogFilter.Filter())
+			r.Use(rateLimiter.Filter(ratelimit.Authenticated))
+			// before the policy, so denied requests are audited as well
+			r.Use(auditRecorder.Filter())
+			r.Use(policyFilter.Filter())
+			r.Post("/auth/logout", authHandler.Logout)
+			r.With(listing).Get("/auth/sessions", authHandler.ListSessions)
+			r.Delete("/auth/sessions/{id}", authHandler.DeleteSession)
+			r.With(structured).Post("/auth/apikeys", authHandler.CreateAPIKey)
+			r.With(listing).Get("/auth/apikeys", authHandler.ListAPIKeys)
+			r.Delete("/auth/apikeys/{id}", authHandler.RevokeAPIKey)
+			r.With(structured).Get("/admin/policy/check", adminHandler.PolicyCheck)
+			r.With(tenant.RequireTenant(), listing).Get("/audit", adminHandler.ListAuditEvents)
+		})
 	})
 
-	return r
+	doc, err := openapi.Build(&openapi.Info{Title: "lake-go", Version: "v1"}, r, operations())
+	if doc == nil {
+		return nil, err
+	}
+	if err != nil {
+		// router/openapi_test.go fails on it, at runtime a stale document is better than no service
+		logutil.GetLogger("ProvideRoutes").Warne(ctx, "build openapi document found error", err)
+	}
+	if spec, err = openapi.Handler(doc); err != nil {
+		return nil, err
+	}
+	return r, nil
 }
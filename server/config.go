@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"github.com/google/wire"
+	"github.com/tyeryan/l-common-util/config"
+	"time"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvideServerConfig,
+	)
+)
+
+type ServerConfig struct {
+	Addr string `configdefault:":8080" configstruct:"SERVER_CONFIG_ADDR" configvalidate:"required,hostport"`
+	// TLSCertFile and TLSKeyFile enable https when both are set, the pair is reloaded when the files change
+	TLSCertFile            string `configstruct:"SERVER_CONFIG_TLS_CERT_FILE"`
+	TLSKeyFile             string `configstruct:"SERVER_CONFIG_TLS_KEY_FILE"`
+	TLSReloadIntervalInSec int32  `configdefault:"30" configstruct:"SERVER_CONFIG_TLS_RELOAD_INTERVAL_IN_SEC" configvalidate:"duration=1s.."`
+	ReadHeaderTimeoutInSec int32  `configdefault:"10" configstruct:"SERVER_CONFIG_READ_HEADER_TIMEOUT_IN_SEC" configvalidate:"duration=1s.."`
+	ReadTimeoutInSec       int32  `configdefault:"30" configstruct:"SERVER_CONFIG_READ_TIMEOUT_IN_SEC" configvalidate:"duration=0s.."`
+	WriteTimeoutInSec      int32  `configdefault:"75" configstruct:"SERVER_CONFIG_WRITE_TIMEOUT_IN_SEC" configvalidate:"duration=0s.."`
+	IdleTimeoutInSec       int32  `configdefault:"120" configstruct:"SERVER_CONFIG_IDLE_TIMEOUT_IN_SEC" configvalidate:"duration=0s.."`
+	MaxHeaderBytes         int32  `configdefault:"1048576" configstruct:"SERVER_CONFIG_MAX_HEADER_BYTES" configvalidate:"min=1024"`
+	// RequestTimeoutInSec is the deadline of the request context, keep it below WriteTimeoutInSec
+	// so handlers can still answer once it is reached
+	RequestTimeoutInSec int32 `configdefault:"60" configstruct:"SERVER_CONFIG_REQUEST_TIMEOUT_IN_SEC" configvalidate:"duration=1s.."`
+	ShutdownGraceInSec  int32 `configdefault:"5" configstruct:"SERVER_CONFIG_SHUTDOWN_GRACE_IN_SEC" configvalidate:"duration=0s..10m"`
+	// AdminAddr serves /metrics, pprof and the runtime admin routes apart from the api. It only
+	// listens on loopback by default, the chart opens it to the pod ip for prometheus.
+	AdminAddr string `configdefault:"127.0.0.1:9090" configstruct:"SERVER_CONFIG_ADMIN_ADDR" configvalidate:"hostport"`
+	// AdminToken guards the admin routes but /metrics, without it they only answer localhost
+	AdminToken string `configstruct:"SERVER_CONFIG_ADMIN_TOKEN"`
+}
+
+func ProvideServerConfig(ctx context.Context, configStore config.ConfigStore) (*ServerConfig, error) {
+	cnf := ServerConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
+}
+
+func (cnf *ServerConfig) TLSEnabled() bool {
+	return cnf.TLSCertFile != "" && cnf.TLSKeyFile != ""
+}
+
+func (cnf *ServerConfig) RequestTimeout() time.Duration {
+	return seconds(cnf.RequestTimeoutInSec)
+}
+
+func (cnf *ServerConfig) ShutdownGrace() time.Duration {
+	return seconds(cnf.ShutdownGraceInSec)
+}
+
+func seconds(sec int32) time.Duration {
+	return time.Duration(sec) * time.Second
+}
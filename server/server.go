@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+)
+
+// NewHTTPServer builds the http server from the config, the tls certificate is loaded
+// up front so a bad pair fails the start instead of the first handshake
+func NewHTTPServer(ctx context.Context, cnf *ServerConfig, handler http.Handler) (*http.Server, error) {
+	httpServer := &http.Server{
+		Addr:              cnf.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: seconds(cnf.ReadHeaderTimeoutInSec),
+		ReadTimeout:       seconds(cnf.ReadTimeoutInSec),
+		WriteTimeout:      seconds(cnf.WriteTimeoutInSec),
+		IdleTimeout:       seconds(cnf.IdleTimeoutInSec),
+		MaxHeaderBytes:    int(cnf.MaxHeaderBytes),
+	}
+
+	if cnf.TLSEnabled() {
+		reloader, err := newCertReloader(ctx, cnf.TLSCertFile, cnf.TLSKeyFile, seconds(cnf.TLSReloadIntervalInSec))
+		if err != nil {
+			return nil, err
+		}
+		httpServer.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+	}
+	return httpServer, nil
+}
+
+// ListenAndServe serves https when tls is configured, plain http otherwise
+func ListenAndServe(cnf *ServerConfig, httpServer *http.Server) error {
+	if cnf.TLSEnabled() {
+		// the certificate comes from TLSConfig.GetCertificate
+		return httpServer.ListenAndServeTLS("", "")
+	}
+	return httpServer.ListenAndServe()
+}
+
+// NewAdminServer builds the plain http server of the admin listener, it shares the timeouts of the api
+func NewAdminServer(cnf *ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              cnf.AdminAddr,
+		Handler:           handler,
+		ReadHeaderTimeout: seconds(cnf.ReadHeaderTimeoutInSec),
+		ReadTimeout:       seconds(cnf.ReadTimeoutInSec),
+		WriteTimeout:      seconds(cnf.WriteTimeoutInSec),
+		IdleTimeout:       seconds(cnf.IdleTimeoutInSec),
+		MaxHeaderBytes:    int(cnf.MaxHeaderBytes),
+	}
+}
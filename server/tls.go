@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"lake-go/filereload"
+	"time"
+)
+
+// certReloader serves the certificate pair from disk, renewed certificates are picked up
+// without a restart
+type certReloader struct {
+	cert *filereload.Reloader[*tls.Certificate]
+}
+
+func newCertReloader(ctx context.Context, certFile string, keyFile string, reloadInterval time.Duration) (*certReloader, error) {
+	cert, err := filereload.New(ctx, "tls certificate "+certFile, reloadInterval,
+		func() (time.Time, error) { return filereload.ModTime(certFile, keyFile) },
+		func() (*tls.Certificate, error) {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return nil, err
+			}
+			return &cert, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return &certReloader{cert: cert}, nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate
+func (cr *certReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return cr.cert.Get(hello.Context()), nil
+}
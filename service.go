@@ -0,0 +1,57 @@
+package main
+
+import (
+	"lake-go/audit"
+	"lake-go/config"
+	"lake-go/filter"
+	"lake-go/grpcclient"
+	"lake-go/grpcserver"
+	"lake-go/health"
+	"lake-go/migrate"
+	"lake-go/policy"
+	"lake-go/ratelimit"
+	"lake-go/router"
+	"lake-go/server"
+	"lake-go/tracing"
+	"net/http"
+)
+
+// service holds what main needs besides the routes to run and stop the http and grpc server
+type service struct {
+	handler        http.Handler
+	healthRegistry *health.Registry
+	healthConfig   *health.HealthConfig
+	serverConfig   *server.ServerConfig
+	grpcServer     *grpcserver.Server
+	adminRoutes    *router.AdminRoutes
+	tracing        *tracing.Tracing
+	auditRecorder  *audit.Recorder
+	migrator       *migrate.Migrator
+	migrateConfig  *migrate.MigrateConfig
+	// configWatcher and the components below it subscribe to config reloads, see subscribeConfig
+	configWatcher *config.Watcher
+	cors          *filter.CORS
+	rateLimiter   *ratelimit.Limiter
+	lauthTimeout  *grpcclient.LAuthTimeout
+	policyEngine  *policy.Engine
+}
+
+func provideService(handler http.Handler, healthRegistry *health.Registry, healthConfig *health.HealthConfig, serverConfig *server.ServerConfig, grpcServer *grpcserver.Server, adminRoutes *router.AdminRoutes, tracing *tracing.Tracing, auditRecorder *audit.Recorder, migrator *migrate.Migrator, migrateConfig *migrate.MigrateConfig, configWatcher *config.Watcher, cors *filter.CORS, rateLimiter *ratelimit.Limiter, lauthTimeout *grpcclient.LAuthTimeout, policyEngine *policy.Engine) *service {
+	return &service{
+		handler:        handler,
+		healthRegistry: healthRegistry,
+		healthConfig:   healthConfig,
+		serverConfig:   serverConfig,
+		grpcServer:     grpcServer,
+		adminRoutes:    adminRoutes,
+		tracing:        tracing,
+		auditRecorder:  auditRecorder,
+		migrator:       migrator,
+		migrateConfig:  migrateConfig,
+		configWatcher:  configWatcher,
+		cors:           cors,
+		rateLimiter:    rateLimiter,
+		lauthTimeout:   lauthTimeout,
+		policyEngine:   policyEngine,
+	}
+}
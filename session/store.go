@@ -0,0 +1,252 @@
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/go-redis/redis"
+	"github.com/google/wire"
+	"github.com/tyeryan/l-common-util/cache"
+	"github.com/tyeryan/l-common-util/config"
+	"github.com/vmihailenco/msgpack"
+	"lake-go/tenant"
+	"lake-go/tracing"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvideSessionConfig,
+		ProvideStore,
+	)
+
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+const (
+	sessionIndexKeyPrefix = "lake-go:sessions:"
+	revokedKeyPrefix      = "lake-go:revoked-session:"
+)
+
+type SessionConfig struct {
+	// DefaultTTLInSec bounds the index entry of tokens which don't carry an exp claim,
+	// their revocation is kept until it is deleted
+	DefaultTTLInSec int32 `configdefault:"86400" configstruct:"SESSION_CONFIG_DEFAULT_TTL_IN_SEC" configvalidate:"duration=1m.."`
+}
+
+// Session is a token issued through the login endpoint
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"userId"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	UserAgent  string    `json:"userAgent,omitempty"`
+	RemoteAddr string    `json:"remoteAddr,omitempty"`
+	// Opaque tokens have no exp claim, ExpiresAt is derived from DefaultTTLInSec
+	Opaque bool `json:"-"`
+}
+
+// Store keeps a per-user session index and the revocation list in the distributed cache.
+// Both expire together with the tokens they describe, revocations of opaque tokens don't expire.
+type Store struct {
+	cnf         *SessionConfig
+	cacheClient cache.DistributedCache
+}
+
+func ProvideSessionConfig(ctx context.Context, configStore config.ConfigStore) (*SessionConfig, error) {
+	cnf := SessionConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
+}
+
+func ProvideStore(ctx context.Context, cnf *SessionConfig, cacheClient cache.DistributedCache) (*Store, error) {
+	return &Store{
+		cnf:         cnf,
+		cacheClient: cacheClient,
+	}, nil
+}
+
+// IDFromToken derives the session id from the token, the raw token is never stored
+func IDFromToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Create registers the token as a new session of the user
+func (s *Store) Create(ctx context.Context, userID string, token string, userAgent string, remoteAddr string) (*Session, error) {
+	now := time.Now()
+	expiresAt, ok := tokenExpiry(token)
+	if !ok {
+		expiresAt = now.Add(time.Duration(s.cnf.DefaultTTLInSec) * time.Second)
+	}
+	session := &Session{
+		ID:         IDFromToken(token),
+		UserID:     userID,
+		CreatedAt:  now,
+		ExpiresAt:  expiresAt,
+		UserAgent:  userAgent,
+		RemoteAddr: remoteAddr,
+		Opaque:     !ok,
+	}
+
+	raw, err := msgpack.Marshal(session)
+	if err != nil {
+		return nil, err
+	}
+
+	key := sessionIndexKey(userID)
+	client := tracing.Redis(ctx, s.cacheClient.GetClient())
+	if err := client.HSet(key, session.ID, raw).Err(); err != nil {
+		return nil, err
+	}
+
+	// the index lives as long as the longest session in it
+	ttl := session.ExpiresAt.Sub(now)
+	if current, err := client.TTL(key).Result(); err == nil && current < ttl {
+		if err := client.Expire(key, ttl).Err(); err != nil {
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+// List returns the active sessions of the user, newest first. Expired sessions are pruned from the index.
+func (s *Store) List(ctx context.Context, userID string) ([]*Session, error) {
+	log := tenant.GetLogger(ctx, "session.Store")
+	key := sessionIndexKey(userID)
+	client := tracing.Redis(ctx, s.cacheClient.GetClient())
+
+	values, err := client.HGetAll(key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sessions := make([]*Session, 0, len(values))
+	var expired []string
+	for id, raw := range values {
+		session := &Session{}
+		if err := msgpack.Unmarshal([]byte(raw), session); err != nil {
+			log.Warne(ctx, "decode session found error", err, "sessionID", id)
+			expired = append(expired, id)
+			continue
+		}
+		if !session.ExpiresAt.After(now) {
+			expired = append(expired, id)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	if len(expired) > 0 {
+		if err := client.HDel(key, expired...).Err(); err != nil {
+			log.Warne(ctx, "prune expired sessions found error", err)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+// Revoke revokes a session of the user by id, ErrSessionNotFound is returned when
+// the user has no such session.
+func (s *Store) Revoke(ctx context.Context, userID string, sessionID string) error {
+	key := sessionIndexKey(userID)
+	client := tracing.Redis(ctx, s.cacheClient.GetClient())
+
+	raw, err := client.HGet(key, sessionID).Bytes()
+	if err == redis.Nil {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	session := &Session{}
+	if err := msgpack.Unmarshal(raw, session); err != nil {
+		return err
+	}
+
+	expiresAt := session.ExpiresAt
+	if session.Opaque {
+		expiresAt = time.Time{}
+	}
+	if err := s.revoke(ctx, sessionID, expiresAt); err != nil {
+		return err
+	}
+	return client.HDel(key, sessionID).Err()
+}
+
+// RevokeToken revokes the session of the token, used on logout
+func (s *Store) RevokeToken(ctx context.Context, userID string, token string) error {
+	sessionID := IDFromToken(token)
+	err := s.Revoke(ctx, userID, sessionID)
+	if err != ErrSessionNotFound {
+		return err
+	}
+	// tokens not issued through our login endpoint have no index entry, still make sure they stop working
+	expiresAt, _ := tokenExpiry(token)
+	return s.revoke(ctx, sessionID, expiresAt)
+}
+
+// IsRevoked checks the revocation list for the session of the token
+func (s *Store) IsRevoked(ctx context.Context, token string) (bool, error) {
+	var revoked bool
+	err := tracing.Cache(ctx, "get", func() error {
+		return s.cacheClient.Get(revokedKeyPrefix+IDFromToken(token), &revoked)
+	})
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+// revoke keeps the revocation until expiresAt, a zero expiresAt keeps it until it is deleted
+// as an opaque token may stay valid at its issuer for any time
+func (s *Store) revoke(ctx context.Context, sessionID string, expiresAt time.Time) error {
+	var ttl time.Duration
+	if !expiresAt.IsZero() {
+		if ttl = time.Until(expiresAt); ttl <= 0 {
+			// already expired, nothing left to revoke
+			return nil
+		}
+	}
+	return tracing.Cache(ctx, "set", func() error {
+		return s.cacheClient.Set(revokedKeyPrefix+sessionID, ttl, true)
+	})
+}
+
+// tokenExpiry reads the exp claim when the token is a JWT, the signature is not checked
+// here as the token has already been accepted by l-auth or AuthFilter. It reports false for
+// opaque tokens.
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
+
+func sessionIndexKey(userID string) string {
+	return sessionIndexKeyPrefix + userID
+}
@@ -0,0 +1,132 @@
+package tenant
+
+import (
+	"context"
+	"github.com/google/wire"
+	"github.com/tyeryan/l-common-util/config"
+	"lake-go/responder"
+	"net"
+	"net/http"
+	"strings"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvideTenantConfig,
+		ProvideResolver,
+	)
+)
+
+const (
+	ErrCodeTenantConflict = "TENANT_CONFLICT"
+	ErrCodeUnknownTenant  = "UNKNOWN_TENANT"
+	ErrCodeTenantRequired = "TENANT_REQUIRED"
+)
+
+type TenantConfig struct {
+	Header string `configdefault:"X-Tenant-Id" configstruct:"TENANT_CONFIG_HEADER" configvalidate:"required"`
+	// BaseDomain requests to <tenant>.<BaseDomain> resolve the tenant from the subdomain
+	BaseDomain string `configstruct:"TENANT_CONFIG_BASE_DOMAIN"`
+	// Allowed known tenants, any tenant is accepted when empty
+	Allowed []string `configstruct:"TENANT_CONFIG_ALLOWED"`
+}
+
+// Resolver resolves the tenant of a request from the tenant header or the subdomain,
+// the tenant claim of the token is applied later by AuthFilter.
+type Resolver struct {
+	cnf *TenantConfig
+}
+
+func ProvideTenantConfig(ctx context.Context, configStore config.ConfigStore) (*TenantConfig, error) {
+	cnf := TenantConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
+}
+
+func ProvideResolver(ctx context.Context, cnf *TenantConfig) (*Resolver, error) {
+	return &Resolver{
+		cnf: cnf,
+	}, nil
+}
+
+// Filter puts the tenant into the context, conflicting or unknown tenants are rejected
+func (res *Resolver) Filter() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			fromHeader := strings.TrimSpace(r.Header.Get(res.cnf.Header))
+			fromHost := res.fromHost(r.Host)
+			if fromHeader != "" && fromHost != "" && fromHeader != fromHost {
+				responder.Error(w, r, responder.NewProblem(http.StatusBadRequest, ErrCodeTenantConflict, "tenant header doesn't match the host"))
+				return
+			}
+
+			id := fromHeader
+			if id == "" {
+				id = fromHost
+			}
+			if id == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !res.Known(id) {
+				responder.Error(w, r, responder.NewProblem(http.StatusBadRequest, ErrCodeUnknownTenant, "unknown tenant"))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithTenant(r.Context(), id)))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Header the name of the tenant header, grpc callers send it as metadata under the lowercase name
+func (res *Resolver) Header() string {
+	return res.cnf.Header
+}
+
+// Known checks the tenant against the allowed tenants
+func (res *Resolver) Known(id string) bool {
+	if len(res.cnf.Allowed) == 0 {
+		return true
+	}
+	for _, allowed := range res.cnf.Allowed {
+		if allowed == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (res *Resolver) fromHost(host string) string {
+	if res.cnf.BaseDomain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	suffix := "." + res.cnf.BaseDomain
+	if !strings.HasSuffix(host, suffix) {
+		return ""
+	}
+	sub := strings.TrimSuffix(host, suffix)
+	if sub == "" || strings.Contains(sub, ".") {
+		return ""
+	}
+	return sub
+}
+
+// RequireTenant rejects requests without a tenant with 403, for routes which only make sense
+// inside one like the audit trail
+func RequireTenant() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := FromContext(r.Context()); !ok {
+				responder.Error(w, r, responder.NewProblem(http.StatusForbidden, ErrCodeTenantRequired, "tenant is required"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
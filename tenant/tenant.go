@@ -0,0 +1,44 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	ctxutil "github.com/tyeryan/l-protocol/context"
+	logutil "github.com/tyeryan/l-protocol/log"
+)
+
+// ID tenant of the request. It is kept in the outgoing grpc metadata by ctxutil.Add,
+// so every downstream call made with the request context carries it to l-auth.
+const ID ctxutil.ContextKey = "x-tenant-id"
+
+var ErrCrossTenant = errors.New("cross tenant access")
+
+// FromContext returns the tenant of the request
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctxutil.Read(ctx, ID)
+	return id, ok && id != ""
+}
+
+// WithTenant puts the tenant into the context
+func WithTenant(ctx context.Context, id string) context.Context {
+	return ctxutil.Add(ctx, ID, id)
+}
+
+// Authorize checks that a resource owned by resourceTenant may be accessed in the tenant of the request
+func Authorize(ctx context.Context, resourceTenant string) error {
+	id, ok := FromContext(ctx)
+	if !ok || id != resourceTenant {
+		return ErrCrossTenant
+	}
+	return nil
+}
+
+// GetLogger returns a logger which tags every line with the tenant of the request,
+// logutil itself only reads the stan and the user id from the context.
+func GetLogger(ctx context.Context, name string) *logutil.Log {
+	log := logutil.GetLogger(name)
+	if id, ok := FromContext(ctx); ok {
+		log.Add("tenant", id)
+	}
+	return log
+}
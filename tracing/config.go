@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+	"github.com/tyeryan/l-common-util/config"
+	"strings"
+)
+
+type TracingConfig struct {
+	// ServiceName, ServiceVersion and Environment describe lake-go to apm and to the otlp collector
+	ServiceName    string `configdefault:"lake-go" configstruct:"TRACING_CONFIG_SERVICE_NAME" configvalidate:"required"`
+	ServiceVersion string `configstruct:"TRACING_CONFIG_SERVICE_VERSION"`
+	Environment    string `configstruct:"TRACING_CONFIG_ENVIRONMENT"`
+	// Labels extra key=value pairs set on every apm transaction and on the otel resource
+	Labels []string `configstruct:"TRACING_CONFIG_LABELS"`
+	// OTLPEnable exports spans over otlp/grpc, independently of elastic apm (APM_ENABLE)
+	OTLPEnable   bool    `configdefault:"false" configstruct:"TRACING_CONFIG_OTLP_ENABLE"`
+	OTLPEndpoint string  `configdefault:"localhost:4317" configstruct:"TRACING_CONFIG_OTLP_ENDPOINT" configvalidate:"hostport"`
+	OTLPInsecure bool    `configdefault:"true" configstruct:"TRACING_CONFIG_OTLP_INSECURE"`
+	SampleRatio  float64 `configdefault:"1" configstruct:"TRACING_CONFIG_SAMPLE_RATIO" configvalidate:"min=0,max=1"`
+}
+
+func ProvideTracingConfig(ctx context.Context, configStore config.ConfigStore) (*TracingConfig, error) {
+	cnf := TracingConfig{}
+	if err := configStore.GetConfig(&cnf); err != nil {
+		return nil, err
+	}
+	return &cnf, nil
+}
+
+// labels parses Labels, entries without a key are ignored
+func (cnf *TracingConfig) labels() map[string]string {
+	labels := map[string]string{}
+	for _, label := range cnf.Labels {
+		key, value, _ := strings.Cut(label, "=")
+		if key = strings.TrimSpace(key); key != "" {
+			labels[key] = strings.TrimSpace(value)
+		}
+	}
+	return labels
+}
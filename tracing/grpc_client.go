@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// Instrument wraps a connection for generated clients so every call gets a span and carries the
+// traceparent to the callee, the connection helper of l-common-util doesn't take interceptors
+func Instrument(cc grpc.ClientConnInterface) grpc.ClientConnInterface {
+	return &instrumentedConn{ClientConnInterface: cc}
+}
+
+type instrumentedConn struct {
+	grpc.ClientConnInterface
+}
+
+func (c *instrumentedConn) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	ctx, end := StartSpan(ctx, method, "external.grpc", semconv.RPCSystemGRPC, semconv.RPCService(service), semconv.RPCMethod(name))
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	err := c.ClientConnInterface.Invoke(ctx, method, args, reply, opts...)
+	if err != nil {
+		end(status.Convert(err).Err())
+		return err
+	}
+	end(nil)
+	return nil
+}
+
+// metadataCarrier lets the propagator write into grpc metadata
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
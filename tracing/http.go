@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/chi/v5"
+	"go.elastic.co/apm"
+	"go.elastic.co/apm/module/apmhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// unknownRoute names transactions of requests which matched no route, like apmhttp does
+const unknownRoute = "unknown route"
+
+// Filter starts the apm transaction and the otel server span of a request. Both are named after the
+// chi route pattern once the request was served, so it has to run on the router.
+func (t *Tracing) Filter() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := otel.Tracer(instrumentationName).Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(semconv.HTTPMethod(r.Method)))
+			defer span.End()
+
+			tx := apm.TransactionFromContext(ctx)
+			if tx != nil {
+				for key, value := range t.labels {
+					tx.Context.SetLabel(key, value)
+				}
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			route := unknownRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+				span.SetAttributes(semconv.HTTPRoute(route))
+			}
+			name := r.Method + " " + route
+			span.SetName(name)
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			span.SetAttributes(semconv.HTTPStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			if tx != nil {
+				tx.Name = name
+			}
+		}
+		if t.apmTracer == nil {
+			return http.HandlerFunc(fn)
+		}
+		return apmhttp.Wrap(http.HandlerFunc(fn), apmhttp.WithTracer(t.apmTracer))
+	}
+}
@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// Redis binds the client to ctx, the commands run on the returned client are traced as children
+// of the span in ctx. It is a cheap copy and meant to be called per operation.
+func Redis(ctx context.Context, client *redis.ClusterClient) *redis.ClusterClient {
+	client = client.WithContext(ctx)
+	client.WrapProcess(func(process func(redis.Cmder) error) func(redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			_, end := StartSpan(ctx, "redis "+cmd.Name(), "db.redis", semconv.DBSystemRedis, semconv.DBOperation(cmd.Name()))
+			err := process(cmd)
+			end(redisError(err))
+			return err
+		}
+	})
+	client.WrapProcessPipeline(func(process func([]redis.Cmder) error) func([]redis.Cmder) error {
+		return func(cmds []redis.Cmder) error {
+			_, end := StartSpan(ctx, "redis pipeline", "db.redis", semconv.DBSystemRedis, semconv.DBOperation("pipeline"))
+			err := process(cmds)
+			end(redisError(err))
+			return err
+		}
+	})
+	return client
+}
+
+// redisError ignores redis.Nil, a missing key is an answer
+func redisError(err error) error {
+	if err == redis.Nil {
+		return nil
+	}
+	return err
+}
+
+// Cache traces a call of cache.DistributedCache, its Get, Set and Del don't take a context
+func Cache(ctx context.Context, operation string, call func() error) error {
+	_, end := StartSpan(ctx, "cache "+operation, "db.redis", semconv.DBSystemRedis, semconv.DBOperation(operation))
+	err := call()
+	end(redisError(err))
+	return err
+}
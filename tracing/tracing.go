@@ -0,0 +1,120 @@
+package tracing
+
+import (
+	"context"
+	"github.com/google/wire"
+	lapm "github.com/tyeryan/l-common-util/apm"
+	logutil "github.com/tyeryan/l-protocol/log"
+	"go.elastic.co/apm"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	WireSet = wire.NewSet(
+		ProvideTracingConfig,
+		ProvideTracing,
+	)
+)
+
+// instrumentationName names the otel tracer of lake-go spans
+const instrumentationName = "lake-go"
+
+// Tracing owns the elastic apm tracer and the otel tracer provider, either, both or none are enabled.
+// The otel provider is installed globally so packages without a Tracing can start spans.
+type Tracing struct {
+	cnf       *TracingConfig
+	labels    map[string]string
+	apmTracer *apm.Tracer
+	provider  *sdktrace.TracerProvider
+}
+
+func ProvideTracing(ctx context.Context, cnf *TracingConfig, apmConfig *lapm.ApmConfig) (*Tracing, error) {
+	log := logutil.GetLogger("ProvideTracing")
+	t := &Tracing{
+		cnf:    cnf,
+		labels: cnf.labels(),
+	}
+
+	if apmConfig.Enable {
+		// the apm server url and secret still come from the ELASTIC_APM_* environment
+		tracer, err := apm.NewTracerOptions(apm.TracerOptions{
+			ServiceName:        cnf.ServiceName,
+			ServiceVersion:     cnf.ServiceVersion,
+			ServiceEnvironment: cnf.Environment,
+		})
+		if err != nil {
+			log.Errore(ctx, "create apm tracer found error", err)
+			return nil, err
+		}
+		t.apmTracer = tracer
+	}
+
+	if cnf.OTLPEnable {
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cnf.OTLPEndpoint)}
+		if cnf.OTLPInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		// the exporter connects lazily, a missing collector doesn't fail the start
+		exporter, err := otlptracegrpc.New(ctx, opts...)
+		if err != nil {
+			log.Errore(ctx, "create otlp exporter found error", err)
+			return nil, err
+		}
+		attrs := []attribute.KeyValue{semconv.ServiceName(cnf.ServiceName)}
+		if cnf.ServiceVersion != "" {
+			attrs = append(attrs, semconv.ServiceVersion(cnf.ServiceVersion))
+		}
+		if cnf.Environment != "" {
+			attrs = append(attrs, semconv.DeploymentEnvironment(cnf.Environment))
+		}
+		for key, value := range t.labels {
+			attrs = append(attrs, attribute.String(key, value))
+		}
+		t.provider = sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exporter),
+			sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, attrs...)),
+			sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cnf.SampleRatio))),
+		)
+		otel.SetTracerProvider(t.provider)
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	}
+
+	log.Infow(ctx, "tracing configured", "apm", apmConfig.Enable, "otlp", cnf.OTLPEnable, "otlpEndpoint", cnf.OTLPEndpoint)
+	return t, nil
+}
+
+// Shutdown flushes the spans which are still buffered, it is called once the servers stopped
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	if t.apmTracer != nil {
+		t.apmTracer.Flush(ctx.Done())
+		t.apmTracer.Close()
+	}
+	if t.provider != nil {
+		return t.provider.Shutdown(ctx)
+	}
+	return nil
+}
+
+// StartSpan starts a span in both tracers, spans of a disabled tracer are no-ops. The returned
+// func ends the span and marks it failed when err isn't nil.
+func StartSpan(ctx context.Context, name string, spanType string, attrs ...attribute.KeyValue) (context.Context, func(err error)) {
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	apmSpan, ctx := apm.StartSpan(ctx, name, spanType)
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			apmSpan.Outcome = "failure"
+		}
+		apmSpan.End()
+		span.End()
+	}
+}
@@ -11,27 +11,63 @@ import (
 	"github.com/tyeryan/l-common-util/apm"
 	"github.com/tyeryan/l-common-util/cache"
 	"github.com/tyeryan/l-common-util/config"
+	"lake-go/apikey"
+	"lake-go/audit"
+	"lake-go/clientip"
+	config2 "lake-go/config"
+	"lake-go/db"
 	"lake-go/filter"
 	"lake-go/grpcclient"
+	"lake-go/grpcserver"
+	"lake-go/handler/admin"
 	"lake-go/handler/auth"
+	"lake-go/health"
+	"lake-go/loginguard"
+	"lake-go/metrics"
+	"lake-go/migrate"
+	"lake-go/policy"
+	"lake-go/ratelimit"
 	"lake-go/router"
-	"net/http"
+	"lake-go/server"
+	"lake-go/session"
+	"lake-go/tenant"
+	"lake-go/tracing"
 )
 
 // Injectors from inject_service.go:
 
-func injectRoutes(ctx context.Context) (http.Handler, error) {
+func injectService(ctx context.Context) (*service, error) {
 	decoderConfigOption := config.ProvideDecodeOption(ctx)
-	configStore := config.ProvideConfigStoreImpl(ctx, decoderConfigOption)
-	lAuthConfig, err := grpcclient.ProvideLAuthConfig(ctx, configStore)
+	store, err := config2.ProvideConfigStore(ctx, decoderConfigOption)
 	if err != nil {
 		return nil, err
 	}
-	lAuthClient, err := grpcclient.ProvideLAuthClient(ctx, lAuthConfig)
+	authFilterConfig, err := filter.ProvideAuthFilterConfig(ctx, store)
 	if err != nil {
 		return nil, err
 	}
-	redisConfig, err := cache.ProvideRedisConfig(ctx, configStore)
+	lAuthConfig, err := grpcclient.ProvideLAuthConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	lAuthConn, err := grpcclient.ProvideLAuthConn(ctx, lAuthConfig)
+	if err != nil {
+		return nil, err
+	}
+	registry, err := metrics.ProvideRegistry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	grpcClientMetrics, err := metrics.ProvideGRPCClientMetrics(ctx, registry)
+	if err != nil {
+		return nil, err
+	}
+	lAuthTimeout := grpcclient.ProvideLAuthTimeout(ctx, lAuthConfig)
+	lAuthClient, err := grpcclient.ProvideLAuthClient(ctx, lAuthConn, grpcClientMetrics, lAuthTimeout)
+	if err != nil {
+		return nil, err
+	}
+	redisConfig, err := cache.ProvideRedisConfig(ctx, store)
 	if err != nil {
 		return nil, err
 	}
@@ -39,24 +75,229 @@ func injectRoutes(ctx context.Context) (http.Handler, error) {
 	if err != nil {
 		return nil, err
 	}
-	distributedCache, err := cache.ProvideCacheClient(ctx, clusterClient, redisConfig)
+	distributedCache, err := metrics.ProvideCacheClient(ctx, clusterClient, redisConfig, registry)
+	if err != nil {
+		return nil, err
+	}
+	sessionConfig, err := session.ProvideSessionConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	sessionStore, err := session.ProvideStore(ctx, sessionConfig, distributedCache)
 	if err != nil {
 		return nil, err
 	}
-	authFilter, err := filter.ProvideAuthFilter(ctx, lAuthClient, distributedCache)
+	databaseConfig, err := db.ProvideDatabaseConfig(ctx, store)
 	if err != nil {
 		return nil, err
 	}
-	lakeHandler := router.ProvideLakeHandler()
-	authHandler, err := auth.ProvideAuthHandler(ctx, lAuthClient)
+	sqlDB, err := db.ProvideDB(ctx, databaseConfig)
 	if err != nil {
 		return nil, err
 	}
-	apmConfig, err := apm.ProvideApmConfig(ctx, configStore)
+	apikeyStore, err := apikey.ProvideStore(ctx, sqlDB)
+	if err != nil {
+		return nil, err
+	}
+	authFilter, err := filter.ProvideAuthFilter(ctx, authFilterConfig, lAuthClient, distributedCache, sessionStore, apikeyStore)
+	if err != nil {
+		return nil, err
+	}
+	policyConfig, err := policy.ProvidePolicyConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	engine, err := policy.ProvideEngine(ctx, policyConfig)
+	if err != nil {
+		return nil, err
+	}
+	policyFilter := filter.ProvidePolicyFilter(engine)
+	healthConfig, err := health.ProvideHealthConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	healthRegistry := health.ProvideRegistry(ctx, healthConfig, distributedCache, lAuthConn, sqlDB)
+	lakeHandler := router.ProvideLakeHandler(healthRegistry)
+	loginGuardConfig, err := loginguard.ProvideLoginGuardConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	guard, err := loginguard.ProvideGuard(ctx, loginGuardConfig, distributedCache)
+	if err != nil {
+		return nil, err
+	}
+	clientIPConfig, err := clientip.ProvideClientIPConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := clientip.ProvideResolver(ctx, clientIPConfig)
+	if err != nil {
+		return nil, err
+	}
+	auditConfig, err := audit.ProvideAuditConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	auditStore, err := audit.ProvideStore(ctx, sqlDB)
+	if err != nil {
+		return nil, err
+	}
+	recorder, err := audit.ProvideRecorder(ctx, auditConfig, auditStore)
+	if err != nil {
+		return nil, err
+	}
+	authHandler, err := auth.ProvideAuthHandler(ctx, lAuthClient, authFilter, sessionStore, apikeyStore, guard, resolver, recorder)
+	if err != nil {
+		return nil, err
+	}
+	adminHandler, err := admin.ProvideAdminHandler(ctx, engine, auditStore, auditConfig)
+	if err != nil {
+		return nil, err
+	}
+	apmConfig, err := apm.ProvideApmConfig(ctx, store)
 	if err != nil {
 		return nil, err
 	}
 	accessLogFilter := filter.ProvideAccessLogFilter(apmConfig)
-	handler := router.ProvideRoutes(authFilter, lakeHandler, authHandler, apmConfig, accessLogFilter)
-	return handler, nil
+	requestLoggerConfig, err := filter.ProvideRequestLoggerConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	requestLogger, err := filter.ProvideRequestLogger(ctx, requestLoggerConfig)
+	if err != nil {
+		return nil, err
+	}
+	corsConfig, err := filter.ProvideCORSConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	cors, err := filter.ProvideCORS(ctx, corsConfig)
+	if err != nil {
+		return nil, err
+	}
+	tenantConfig, err := tenant.ProvideTenantConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	tenantResolver, err := tenant.ProvideResolver(ctx, tenantConfig)
+	if err != nil {
+		return nil, err
+	}
+	serverConfig, err := server.ProvideServerConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	rateLimitConfig, err := ratelimit.ProvideRateLimitConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	limiter, err := ratelimit.ProvideLimiter(ctx, rateLimitConfig, distributedCache, resolver)
+	if err != nil {
+		return nil, err
+	}
+	httpMetrics, err := metrics.ProvideHTTPMetrics(ctx, registry)
+	if err != nil {
+		return nil, err
+	}
+	tracingConfig, err := tracing.ProvideTracingConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	tracingTracing, err := tracing.ProvideTracing(ctx, tracingConfig, apmConfig)
+	if err != nil {
+		return nil, err
+	}
+	handler, err := router.ProvideRoutes(ctx, authFilter, policyFilter, lakeHandler, authHandler, adminHandler, accessLogFilter, requestLogger, cors, tenantResolver, serverConfig, limiter, httpMetrics, tracingTracing, recorder)
+	if err != nil {
+		return nil, err
+	}
+	grpcServerConfig, err := grpcserver.ProvideGRPCServerConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	lakeAuthServer, err := grpcserver.ProvideLakeAuthServer(ctx, authHandler, sessionStore, apikeyStore, resolver)
+	if err != nil {
+		return nil, err
+	}
+	lakeAdminServer, err := grpcserver.ProvideLakeAdminServer(ctx, engine)
+	if err != nil {
+		return nil, err
+	}
+	grpcserverServer, err := grpcserver.ProvideServer(ctx, grpcServerConfig, authFilter, engine, tenantResolver, recorder, limiter, lakeAuthServer, lakeAdminServer)
+	if err != nil {
+		return nil, err
+	}
+	adminRoutes, err := router.ProvideAdminRoutes(ctx, registry, serverConfig, adminHandler)
+	if err != nil {
+		return nil, err
+	}
+	migrateConfig, err := migrate.ProvideMigrateConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := migrate.ProvideMigrator(ctx, migrateConfig, sqlDB)
+	if err != nil {
+		return nil, err
+	}
+	watcher := config2.ProvideWatcher(ctx, store)
+	mainService := provideService(handler, healthRegistry, healthConfig, serverConfig, grpcserverServer, adminRoutes, tracingTracing, recorder, migrator, migrateConfig, watcher, cors, limiter, lAuthTimeout, engine)
+	return mainService, nil
+}
+
+// injectAuditStore builds only what the audit commands need, no server is started
+func injectAuditStore(ctx context.Context) (*audit.Store, error) {
+	decoderConfigOption := config.ProvideDecodeOption(ctx)
+	store, err := config2.ProvideConfigStore(ctx, decoderConfigOption)
+	if err != nil {
+		return nil, err
+	}
+	databaseConfig, err := db.ProvideDatabaseConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.ProvideDB(ctx, databaseConfig)
+	if err != nil {
+		return nil, err
+	}
+	auditStore, err := audit.ProvideStore(ctx, sqlDB)
+	if err != nil {
+		return nil, err
+	}
+	return auditStore, nil
+}
+
+// injectMigrator builds only what the migrate commands need, no server is started
+func injectMigrator(ctx context.Context) (*migrate.Migrator, error) {
+	decoderConfigOption := config.ProvideDecodeOption(ctx)
+	store, err := config2.ProvideConfigStore(ctx, decoderConfigOption)
+	if err != nil {
+		return nil, err
+	}
+	migrateConfig, err := migrate.ProvideMigrateConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	databaseConfig, err := db.ProvideDatabaseConfig(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.ProvideDB(ctx, databaseConfig)
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := migrate.ProvideMigrator(ctx, migrateConfig, sqlDB)
+	if err != nil {
+		return nil, err
+	}
+	return migrator, nil
+}
+
+// injectConfigStore builds only the config store, to check and print the configs
+func injectConfigStore(ctx context.Context) (*config2.Store, error) {
+	decoderConfigOption := config.ProvideDecodeOption(ctx)
+	store, err := config2.ProvideConfigStore(ctx, decoderConfigOption)
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
 }